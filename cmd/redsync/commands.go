@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rgalanakis/redsync"
+)
+
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// config holds the global flags shared by all commands.
+type config struct {
+	addrs   string
+	expiry  time.Duration
+	tries   int
+	delay   time.Duration
+	service string
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg := &config{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("redsync", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.addrs, "addrs", os.Getenv("REDSYNC_ADDRS"), "comma-separated Redis addresses (host:port or unix socket path)")
+	fs.DurationVar(&cfg.expiry, "expiry", redsync.Blocking().Expiry, "lock expiry")
+	fs.IntVar(&cfg.tries, "tries", redsync.Blocking().Tries, "number of acquisition attempts when waiting for a lock")
+	fs.DurationVar(&cfg.delay, "delay", redsync.Blocking().Delay, "delay between acquisition attempts when waiting for a lock")
+	fs.StringVar(&cfg.service, "service", "redsync-cli", "service name stored in lock metadata")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: redsync -addrs ADDRS [flags] lock|unlock|status|list|force-unlock|exec [ARGS]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 || cfg.addrs == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	commands := map[string]func(*config, []string) int{
+		"lock":         cmdLock,
+		"unlock":       cmdUnlock,
+		"status":       cmdStatus,
+		"list":         cmdList,
+		"force-unlock": cmdForceUnlock,
+		"exec":         cmdExec,
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "redsync: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+	return cmd(cfg, fs.Args()[1:])
+}
+
+// dialer returns the Dialer for an address: a Unix socket if it is a path, TCP otherwise.
+func dialer(addr string) redsync.Dialer {
+	if strings.HasPrefix(addr, "unix:") {
+		return redsync.UnixDialer(strings.TrimPrefix(addr, "unix:"))
+	}
+	if strings.HasPrefix(addr, "/") {
+		return redsync.UnixDialer(addr)
+	}
+	return redsync.TcpDialer(strings.TrimPrefix(addr, "tcp:"))
+}
+
+func (cfg *config) redsync() *redsync.Redsync {
+	var pools []*redis.Pool
+	for _, addr := range strings.Split(cfg.addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		pools = append(pools, &redis.Pool{Dial: dialer(addr)})
+	}
+	return redsync.New(pools...)
+}
+
+func (cfg *config) mutexOpts(wait bool) redsync.MutexOpts {
+	opts := redsync.NonBlocking()
+	if wait {
+		opts.Tries = cfg.tries
+		opts.Delay = cfg.delay
+	}
+	opts.Expiry = cfg.expiry
+	opts.Metadata = redsync.ProcessMetadata(cfg.service)
+	return opts
+}
+
+// newFlagSet returns a FlagSet for a command, which prints usage to stderr on error.
+func (cfg *config) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(cfg.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(cfg.stderr, "usage: redsync %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func (cfg *config) fail(err error) int {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "redsync: ") {
+		msg = "redsync: " + msg
+	}
+	fmt.Fprintln(cfg.stderr, msg)
+	return exitFail
+}
+
+func cmdLock(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("lock", "[-wait] NAME")
+	wait := fs.Bool("wait", false, "wait for the lock to become available")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	lease, err := cfg.redsync().NewMutex(fs.Arg(0), cfg.mutexOpts(*wait)).Lock()
+	if err != nil {
+		return cfg.fail(err)
+	}
+	fmt.Fprintln(cfg.stdout, lease.Value())
+	return exitOK
+}
+
+func cmdUnlock(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("unlock", "NAME VALUE")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	// Unlock as the owner, rather than with ForceUnlockIf,
+	// so WatchBroken subscribers do not see the lock as broken.
+	lease, err := cfg.redsync().NewMutex(fs.Arg(0), cfg.mutexOpts(false)).Reclaim(fs.Arg(1))
+	if err != nil {
+		return cfg.fail(err)
+	}
+	if !lease.Unlock() {
+		return cfg.fail(redsync.ErrNotHeld)
+	}
+	return exitOK
+}
+
+func cmdStatus(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("status", "NAME...")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	statuses, err := cfg.redsync().Status(fs.Args()...)
+	if err != nil {
+		return cfg.fail(err)
+	}
+	cfg.printStatuses(statuses)
+	return exitOK
+}
+
+func cmdList(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("list", "[PATTERN]")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	pattern := "*"
+	if fs.NArg() == 1 {
+		pattern = fs.Arg(0)
+	}
+	statuses, err := cfg.redsync().List(context.Background(), pattern)
+	if err != nil {
+		return cfg.fail(err)
+	}
+	cfg.printStatuses(statuses)
+	return exitOK
+}
+
+func (cfg *config) printStatuses(statuses []redsync.LockStatus) {
+	w := tabwriter.NewWriter(cfg.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tHELD\tAGREE\tMIN TTL\tMAX TTL\tOWNER")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%d/%d\t%d\t%s\t%s\t%s\n",
+			s.Name, s.Held, s.Nodes, s.Agree, s.MinTTL, s.MaxTTL, formatOwner(s))
+	}
+	w.Flush()
+}
+
+func formatOwner(s redsync.LockStatus) string {
+	if s.Held == 0 {
+		return "-"
+	}
+	md := s.Metadata
+	if md == nil {
+		return "(no metadata)"
+	}
+	owner := fmt.Sprintf("%s@%s:%d", md.Service, md.Hostname, md.PID)
+	if md.RequestID != "" {
+		owner += " request=" + md.RequestID
+	}
+	if !md.AcquiredAt.IsZero() {
+		owner += " since=" + md.AcquiredAt.Format(time.RFC3339)
+	}
+	return owner
+}
+
+func cmdForceUnlock(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("force-unlock", "[-value VALUE] NAME")
+	value := fs.String("value", "", "only delete the lock if it holds this value")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	rs := cfg.redsync()
+	var results []redsync.NodeResult
+	if *value != "" {
+		results = rs.ForceUnlockIf(fs.Arg(0), *value)
+	} else {
+		results = rs.ForceUnlock(fs.Arg(0))
+	}
+	code := exitOK
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			fmt.Fprintf(cfg.stdout, "node %d: error: %v\n", res.Node, res.Err)
+			code = exitFail
+		case res.Deleted:
+			fmt.Fprintf(cfg.stdout, "node %d: deleted\n", res.Node)
+		default:
+			fmt.Fprintf(cfg.stdout, "node %d: not held\n", res.Node)
+		}
+	}
+	return code
+}
+
+func cmdExec(cfg *config, args []string) int {
+	fs := cfg.newFlagSet("exec", "[-wait] NAME -- COMMAND [ARGS...]")
+	wait := fs.Bool("wait", false, "wait for the lock to become available")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	rest := fs.Args()
+	if len(rest) > 1 && rest[1] == "--" {
+		rest = append(rest[:1], rest[2:]...)
+	}
+	if len(rest) < 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	lease, err := cfg.redsync().NewMutex(rest[0], cfg.mutexOpts(*wait)).Lock()
+	if err != nil {
+		return cfg.fail(err)
+	}
+	defer lease.Unlock()
+
+	child := exec.Command(rest[1], rest[2:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = cfg.stdout
+	child.Stderr = cfg.stderr
+	if err := child.Start(); err != nil {
+		return cfg.fail(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- child.Wait()
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(cfg.expiry / 3)
+	defer ticker.Stop()
+
+	lost := false
+	for {
+		select {
+		case err := <-done:
+			if lost {
+				return exitFail
+			}
+			return exitCode(err)
+		case sig := <-signals:
+			child.Process.Signal(sig)
+		case <-ticker.C:
+			if !lost && !lease.Extend() {
+				fmt.Fprintln(cfg.stderr, "redsync: lock lost, terminating command")
+				lost = true
+				child.Process.Signal(syscall.SIGTERM)
+			}
+		}
+	}
+}
+
+// exitCode returns the exit status for the error returned from exec.Cmd.Wait.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return exitFail
+}
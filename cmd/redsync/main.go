@@ -0,0 +1,35 @@
+// Command redsync operates Redsync locks from the command line.
+//
+// It takes the same list of Redis servers as the Go services using the locks,
+// so shell scripts and cron jobs can be guarded by the same Redlock quorum.
+//
+// Usage:
+//
+//	redsync -addrs ADDRS [flags] COMMAND [ARGS]
+//
+// ADDRS is a comma-separated list of Redis addresses. Addresses like "localhost:6379"
+// are dialed over TCP (like redsync.TcpDialer); addresses starting with "/" or "unix:"
+// are dialed as Unix sockets (like redsync.UnixDialer).
+// If -addrs is not given, the REDSYNC_ADDRS environment variable is used.
+//
+// Commands:
+//
+//	lock [-wait] NAME             acquire a lock, and print its value
+//	unlock NAME VALUE             release a lock acquired with lock
+//	status NAME...                show the state of locks on all servers
+//	list [PATTERN]                show the state of all locks matching PATTERN (default "*")
+//	force-unlock [-value V] NAME  delete a lock regardless of its holder
+//	exec [-wait] NAME -- CMD...   run CMD while holding the lock, extending it until CMD exits
+//
+// The exit status is 0 on success, 1 if a lock could not be acquired or released
+// or another error occurred, and 2 for usage errors.
+// exec exits with the exit status of CMD.
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
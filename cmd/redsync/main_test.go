@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRedsyncCommand(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redsync Command Suite")
+}
+
+var _ = Describe("redsync command", func() {
+	var stdout, stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	It("requires addresses and a command", func() {
+		Expect(run([]string{"lock", "x"}, stdout, stderr)).To(Equal(exitUsage))
+		Expect(run([]string{"-addrs", "localhost:6379"}, stdout, stderr)).To(Equal(exitUsage))
+		Expect(stderr.String()).To(ContainSubstring("usage: redsync"))
+	})
+
+	It("rejects unknown commands", func() {
+		Expect(run([]string{"-addrs", "localhost:6379", "frob"}, stdout, stderr)).To(Equal(exitUsage))
+		Expect(stderr.String()).To(ContainSubstring(`unknown command "frob"`))
+	})
+
+	It("validates command arguments", func() {
+		Expect(run([]string{"-addrs", "localhost:6379", "unlock", "x"}, stdout, stderr)).To(Equal(exitUsage))
+		Expect(run([]string{"-addrs", "localhost:6379", "exec", "x", "--"}, stdout, stderr)).To(Equal(exitUsage))
+		Expect(stderr.String()).To(ContainSubstring("usage: redsync exec"))
+	})
+
+	It("reports errors talking to redis", func() {
+		Expect(run([]string{"-addrs", "127.0.0.1:1", "lock", "x"}, stdout, stderr)).To(Equal(exitFail))
+		Expect(stderr.String()).To(ContainSubstring("redsync: dial tcp"))
+		Expect(stdout.String()).To(BeEmpty())
+	})
+
+	It("does not run the command if the lock cannot be acquired", func() {
+		code := run([]string{"-addrs", "unix:/nonexistent.sock", "exec", "x", "--", "touch", "/tmp/never"}, stdout, stderr)
+		Expect(code).To(Equal(exitFail))
+		Expect("/tmp/never").ToNot(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,231 @@
+package redsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Cond is a condition variable, like sync.Cond, for processes waiting for or announcing
+// a change to state guarded by a Mutex.
+// Use Redsync.NewCond to create one.
+//
+// Waiters are kept in a sorted set on every node, and notified using pub/sub.
+// Nodes may disagree about which waiter Signal should wake, so it can wake more than one.
+// As with sync.Cond, callers should call Wait in a loop that checks the condition.
+type Cond struct {
+	rs    *Redsync
+	mutex *Mutex
+
+	// Expiry is how long a waiter is remembered if its process stops refreshing it, for example if it dies.
+	// Waiters refresh every third of Expiry, and also check whether they missed a notification.
+	// Defaults to 8s.
+	Expiry time.Duration
+}
+
+// NewCond returns a Cond for the state guarded by mutex.
+// All processes using the same mutex name share the Cond.
+func (r *Redsync) NewCond(mutex *Mutex) *Cond {
+	return &Cond{rs: r, mutex: mutex, Expiry: 8 * time.Second}
+}
+
+var condWaitScript = redis.NewScript(1, `
+	if ARGV[3] == "1" and not redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+		return 0
+	end
+	redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+	redis.call("PEXPIRE", KEYS[1], ARGV[4])
+	return 1
+`)
+
+var condSignalScript = redis.NewScript(1, `
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+	local waiters = {ARGV[4]}
+	if ARGV[4] == "" then
+		waiters = redis.call("ZRANGE", KEYS[1], 0, ARGV[3])
+	end
+	for _, waiter in ipairs(waiters) do
+		redis.call("ZREM", KEYS[1], waiter)
+		redis.call("PUBLISH", ARGV[2], waiter)
+	end
+	return waiters
+`)
+
+// Wait unlocks lease, which must hold the Cond's Mutex, and waits until woken by Signal or Broadcast.
+// It then locks the Mutex again, and returns the new Lease.
+// The Cond is told about the waiter before lease is unlocked,
+// so a Signal sent by the next holder of the Mutex is not missed.
+// If Wait returns an error, including when ctx is done, the Mutex is not locked.
+func (c *Cond) Wait(ctx context.Context, lease *Lease) (*Lease, error) {
+	ticket, err := RandomValue()
+	if err != nil {
+		lease.Unlock()
+		return nil, err
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	notified, err := subscribe(subCtx, c.rs.pools, c.channel())
+	if err != nil {
+		lease.Unlock()
+		return nil, err
+	}
+	if n, _, err := c.register(ticket, false); n < c.mutex.quorum {
+		c.remove(ticket)
+		lease.Unlock()
+		if err == nil {
+			err = ErrFailed
+		}
+		return nil, err
+	}
+	lease.Unlock()
+
+	if err := c.waitFor(ctx, ticket, notified); err != nil {
+		// Pass on a Signal that reached this waiter too late, so it wakes someone else.
+		if c.remove(ticket) {
+			c.Signal()
+		}
+		return nil, err
+	}
+	c.remove(ticket)
+	return c.relock(ctx)
+}
+
+// waitFor blocks until ticket is notified, or was removed from any node by a notifier.
+func (c *Cond) waitFor(ctx context.Context, ticket string, notified <-chan []byte) error {
+	ticker := time.NewTicker(c.Expiry / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-notified:
+			if !ok {
+				// All subscriptions failed; rely on refreshing.
+				notified = nil
+			} else if string(msg) == ticket {
+				return nil
+			}
+		case <-ticker.C:
+			if _, notified, _ := c.register(ticket, true); notified {
+				return nil
+			}
+		}
+	}
+}
+
+// relock locks the Mutex again, retrying every Delay of the Mutex until ctx is done.
+func (c *Cond) relock(ctx context.Context) (*Lease, error) {
+	for {
+		lease, err := c.mutex.Lock()
+		if err != ErrFailed {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.mutex.delay):
+		}
+	}
+}
+
+// Signal wakes one process waiting on the Cond, if there is any.
+// It returns an error if no quorum of nodes could be notified.
+func (c *Cond) Signal() error {
+	return c.notify(0)
+}
+
+// Broadcast wakes all processes waiting on the Cond.
+// It returns an error if no quorum of nodes could be notified.
+func (c *Cond) Broadcast() error {
+	return c.notify(-1)
+}
+
+// notify wakes the first last+1 waiters on every node, or all of them if last is -1.
+// When waking one waiter, the one chosen by the first node is woken on every node,
+// since a woken waiter removes itself from nodes that were not notified yet.
+func (c *Cond) notify(last int) error {
+	n := 0
+	ticket := ""
+	var firstErr error
+	for _, pool := range c.rs.pools {
+		conn := pool.Get()
+		woken, err := redis.Strings(condSignalScript.Do(conn, c.key(), nowMillis(), c.channel(), last, ticket))
+		conn.Close()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		n++
+		if last == 0 && ticket == "" && len(woken) > 0 {
+			ticket = woken[0]
+		}
+	}
+	if n < c.mutex.quorum {
+		if firstErr != nil {
+			return firstErr
+		}
+		return ErrFailed
+	}
+	return nil
+}
+
+// register adds ticket to the waiters on every node, or if refresh is true,
+// refreshes it on the nodes where it was not yet notified.
+// It returns the number of nodes where the ticket is waiting,
+// and whether any node no longer had it because it was notified.
+func (c *Cond) register(ticket string, refresh bool) (int, bool, error) {
+	deadline := nowMillis() + int64(c.Expiry/time.Millisecond)
+	flag := "0"
+	if refresh {
+		flag = "1"
+	}
+	n := 0
+	notified := false
+	var firstErr error
+	for _, pool := range c.rs.pools {
+		conn := pool.Get()
+		ok, err := redis.Bool(condWaitScript.Do(conn, c.key(), ticket, deadline, flag, int(2*c.Expiry/time.Millisecond)))
+		conn.Close()
+		switch {
+		case err != nil:
+			if firstErr == nil {
+				firstErr = err
+			}
+		case ok:
+			n++
+		default:
+			notified = true
+		}
+	}
+	return n, notified, firstErr
+}
+
+// remove removes ticket from the waiters on every node,
+// and returns whether any node no longer had it because it was notified.
+func (c *Cond) remove(ticket string) bool {
+	notified := false
+	for _, pool := range c.rs.pools {
+		conn := pool.Get()
+		removed, err := redis.Int(conn.Do("ZREM", c.key(), ticket))
+		conn.Close()
+		if err == nil && removed == 0 {
+			notified = true
+		}
+	}
+	return notified
+}
+
+func (c *Cond) key() string {
+	return c.mutex.name + ":cond"
+}
+
+func (c *Cond) channel() string {
+	return "redsync:cond:" + c.mutex.name
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,117 @@
+package redsync_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Cond", func() {
+	opts := redsync.Blocking()
+	opts.Delay = 10 * time.Millisecond
+
+	// waiter locks name and waits on its Cond in a new goroutine,
+	// sending the result of Wait once it returns.
+	waiter := func(name string, ready *sync.WaitGroup) <-chan error {
+		woken := make(chan error, 1)
+		ready.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			rs := redsync.New(tr.Pools(3)...)
+			mutex := rs.NewMutex(name, opts)
+			lease := mustLock(mutex)
+			cond := rs.NewCond(mutex)
+			ready.Done()
+			lease, err := cond.Wait(context.Background(), lease)
+			if err == nil {
+				Expect(lease.Unlock()).To(BeTrue())
+			}
+			woken <- err
+		}()
+		return woken
+	}
+
+	newCond := func(name string) (*redsync.Mutex, *redsync.Cond) {
+		rs := redsync.New(tr.Pools(3)...)
+		mutex := rs.NewMutex(name, opts)
+		return mutex, rs.NewCond(mutex)
+	}
+
+	It("wakes one waiter on Signal, with the mutex locked", func() {
+		var ready sync.WaitGroup
+		w1 := waiter("test-cond-signal", &ready)
+		w2 := waiter("test-cond-signal", &ready)
+		ready.Wait()
+
+		mutex, cond := newCond("test-cond-signal")
+		// Locking waits for both waiters to release the mutex in Wait.
+		lease := mustLock(mutex)
+		Expect(cond.Signal()).To(Succeed())
+		Expect(lease.Unlock()).To(BeTrue())
+
+		var woken <-chan error
+		select {
+		case err := <-w1:
+			Expect(err).ToNot(HaveOccurred())
+			woken = w2
+		case err := <-w2:
+			Expect(err).ToNot(HaveOccurred())
+			woken = w1
+		case <-time.After(5 * time.Second):
+			Fail("no waiter was woken")
+		}
+		Consistently(woken, 200*time.Millisecond).ShouldNot(Receive())
+
+		Expect(cond.Signal()).To(Succeed())
+		Eventually(woken).Should(Receive(BeNil()))
+	})
+
+	It("wakes all waiters on Broadcast", func() {
+		var ready sync.WaitGroup
+		var waiters []<-chan error
+		for i := 0; i < 3; i++ {
+			waiters = append(waiters, waiter("test-cond-broadcast", &ready))
+		}
+		ready.Wait()
+
+		mutex, cond := newCond("test-cond-broadcast")
+		lease := mustLock(mutex)
+		Expect(cond.Broadcast()).To(Succeed())
+		Expect(lease.Unlock()).To(BeTrue())
+		for _, w := range waiters {
+			Eventually(w, 5*time.Second).Should(Receive(BeNil()))
+		}
+	})
+
+	It("does nothing on Signal without waiters", func() {
+		_, cond := newCond("test-cond-none")
+		Expect(cond.Signal()).To(Succeed())
+
+		var ready sync.WaitGroup
+		w := waiter("test-cond-none", &ready)
+		ready.Wait()
+		Consistently(w, 200*time.Millisecond).ShouldNot(Receive())
+
+		mutex, cond := newCond("test-cond-none")
+		lease := mustLock(mutex)
+		Expect(cond.Signal()).To(Succeed())
+		Expect(lease.Unlock()).To(BeTrue())
+		Eventually(w, 5*time.Second).Should(Receive(BeNil()))
+	})
+
+	It("returns unlocked when ctx is done", func() {
+		mutex, cond := newCond("test-cond-ctx")
+		lease := mustLock(mutex)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		lease, err := cond.Wait(ctx, lease)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(lease).To(BeNil())
+
+		Expect(mustLock(mutex).Unlock()).To(BeTrue())
+	})
+})
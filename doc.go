@@ -3,6 +3,47 @@
 //
 // See examples for suggestions on how to use the lock.
 //
+// Mutexes and leases
+//
+// A Mutex is an immutable definition of a lock, and can be shared between goroutines,
+// for example as a package-level variable. Mutex.Lock returns a Lease, which holds the lock
+// and its random value, and is used to extend and unlock it.
+// MultiMutex and PathMutex also return a Lease from Lock.
+// Lease.Valid and Lease.Remaining are computed locally from the lock's expiry;
+// Lease.IsHeld checks on a quorum of nodes that the lock has not been lost.
+// A lease can be handed over to another process with Lease.Export and Redsync.Adopt,
+// and a restarted process that persisted its lease's Value can take its lock back with Mutex.Reclaim.
+// Redsync tracks the leases acquired through it; Redsync.Close releases them when a process shuts down,
+// and Redsync.CloseOnSignal does so on SIGTERM.
+// A Session holds many locks and extends them together in one keepalive loop.
+// With MutexOpts.Local, goroutines of a process that lock the same name queue locally,
+// so only one of them at a time competes for the lock in Redis.
+// Lease.Guarded and Lease.GuardedDo write data in the same Redis as the lock
+// only if the lock is still held, atomically.
+// With Go 1.18 or later, Guarded wraps this into a typed read-modify-write of a JSON value.
+//
+// Inspecting locks
+//
+// Mutexes can be created with owner Metadata (hostname, PID, service, request ID, labels),
+// which is stored alongside the random lock value.
+// Redsync.Inspect returns the metadata and remaining TTL of the current holder of a lock,
+// which is useful to find out who is holding a stuck lock.
+// Lock tokens can be signed with HMACValueGenerator, so Inspect can verify which service created a lock
+// when Redsync.ValueVerifier is set.
+// Redsync.Status and Redsync.List report the state of lock keys on every node,
+// including keys that exist on only a minority of nodes.
+// Stuck locks can be cleared with Redsync.ForceUnlock or Redsync.ForceUnlockIf;
+// former holders can find out about it using Redsync.WatchBroken.
+//
+// Coordinating work
+//
+// Redsync.Once runs a function successfully at most once across processes,
+// and Redsync.Group shares a single computation of a result between concurrent callers
+// across processes, like a distributed singleflight.
+// Redsync.CountDownLatch and Redsync.Barrier let processes wait for each other,
+// like their java.util.concurrent namesakes.
+// Redsync.NewCond returns a condition variable for the state guarded by a Mutex, like sync.Cond.
+//
 // Testing with locks
 //
 // This package uses a combination of testing against real redis servers using tempredis,
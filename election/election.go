@@ -0,0 +1,241 @@
+// Package election implements leader election on top of Redsync locks.
+//
+// A process becomes leader by acquiring a lock, which it renews in the background
+// for as long as it stays leader. The leader's identity is stored in the lock's Metadata,
+// so every process (and the redsync admin tools) can see who the leader is.
+//
+//	e := election.New(rs, "report-leader", election.Opts{})
+//	if err := e.Campaign(ctx); err != nil {
+//		return err
+//	}
+//	defer e.Resign()
+//	select {
+//	case <-e.Done():
+//		// Leadership was lost; stop doing leader work.
+//	case <-ctx.Done():
+//	}
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rgalanakis/redsync"
+)
+
+// ErrNoLeader is returned from Leader when there is no leader.
+var ErrNoLeader = errors.New("redsync: no leader elected")
+
+// Opts are the options for an Election.
+type Opts struct {
+	// Expiry is the expiry of the leader lock. The lock is extended every Expiry/3,
+	// so if the leader dies, a new leader is elected after at most Expiry. Defaults to 8s.
+	Expiry time.Duration
+	// RetryDelay is the time Campaign waits between attempts to become leader. Defaults to 500ms.
+	RetryDelay time.Duration
+	// PollInterval is the time between checks for leader changes in Observe. Defaults to 1s.
+	PollInterval time.Duration
+	// Identity identifies this process as leader, and is returned from Leader.
+	// Defaults to redsync.ProcessMetadata with the name of the election as service.
+	Identity *redsync.Metadata
+}
+
+// Election is a leader election among processes using the same name.
+// An Election is safe to use from multiple goroutines.
+type Election struct {
+	rs   *redsync.Redsync
+	name string
+	opts Opts
+
+	// campaign serializes calls to Campaign.
+	campaign sync.Mutex
+	mu       sync.Mutex
+	term     *term
+}
+
+// term is a single period of leadership.
+type term struct {
+	lease *redsync.Lease
+	// stop is closed to stop renewing the lock.
+	stop chan struct{}
+	// renewing is closed when the lock is no longer being extended.
+	renewing <-chan struct{}
+	// done is closed when leadership ends.
+	done chan struct{}
+	end  sync.Once
+}
+
+func (t *term) close() {
+	t.end.Do(func() { close(t.done) })
+}
+
+// closed is returned from Done when there is no term.
+var closed = make(chan struct{})
+
+func init() {
+	close(closed)
+}
+
+// New returns an Election using the lock with the given name.
+func New(rs *redsync.Redsync, name string, opts Opts) *Election {
+	if opts.Expiry == 0 {
+		opts.Expiry = 8 * time.Second
+	}
+	if opts.RetryDelay == 0 {
+		opts.RetryDelay = 500 * time.Millisecond
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Identity == nil {
+		opts.Identity = redsync.ProcessMetadata(name)
+	}
+	return &Election{rs: rs, name: name, opts: opts}
+}
+
+// Campaign blocks until this process becomes leader, or ctx is done.
+// Once leader, the leader lock is extended in the background
+// until Resign is called or the lock cannot be extended; see Done.
+// Campaign returns nil immediately if this process is already leader.
+// Errors other than contention for the lock are returned, rather than retried.
+func (e *Election) Campaign(ctx context.Context) error {
+	e.campaign.Lock()
+	defer e.campaign.Unlock()
+	if e.IsLeader() {
+		return nil
+	}
+	opts := redsync.NonBlocking()
+	opts.Expiry = e.opts.Expiry
+	opts.Metadata = e.opts.Identity
+	mutex := e.rs.NewMutex(e.name, opts)
+	var lease *redsync.Lease
+	for {
+		var err error
+		lease, err = mutex.Lock()
+		if err == nil {
+			break
+		}
+		if err != redsync.ErrFailed {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.opts.RetryDelay):
+		}
+	}
+	t := &term{
+		lease: lease,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	t.renewing = lease.KeepAlive(t.stop)
+	go func() {
+		<-t.renewing
+		t.close()
+	}()
+	e.mu.Lock()
+	e.term = t
+	e.mu.Unlock()
+	return nil
+}
+
+// Resign gives up leadership, if this process is leader.
+// It returns redsync.ErrNotHeld if the leader lock was already lost.
+func (e *Election) Resign() error {
+	e.mu.Lock()
+	t := e.term
+	e.term = nil
+	e.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+	close(t.stop)
+	<-t.renewing
+	released := t.lease.Unlock()
+	t.close()
+	if !released {
+		return redsync.ErrNotHeld
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when this process stops being leader,
+// either through Resign or because the leader lock could not be extended.
+// The channel is already closed if this process is not leader.
+func (e *Election) Done() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.term == nil {
+		return closed
+	}
+	return e.term.done
+}
+
+// IsLeader returns true if this process is leader.
+func (e *Election) IsLeader() bool {
+	select {
+	case <-e.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// Leader returns the identity of the current leader,
+// or ErrNoLeader if there is no leader.
+func (e *Election) Leader() (*redsync.Metadata, error) {
+	info, err := e.rs.Inspect(e.name)
+	if err == redsync.ErrNotHeld {
+		return nil, ErrNoLeader
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info.Metadata, nil
+}
+
+// Observe returns a channel that receives the identity of the leader whenever it changes,
+// starting with the current leader. It receives nil when there is no leader.
+// Changes are found by polling every Opts.PollInterval,
+// so a leader that is replaced quickly may not be observed.
+// Errors talking to Redis are ignored until the next poll.
+// The channel is closed when ctx is done.
+func (e *Election) Observe(ctx context.Context) <-chan *redsync.Metadata {
+	leaders := make(chan *redsync.Metadata)
+	go func() {
+		defer close(leaders)
+		ticker := time.NewTicker(e.opts.PollInterval)
+		defer ticker.Stop()
+		first := true
+		last := ""
+		for {
+			info, err := e.rs.Inspect(e.name)
+			if err == nil || err == redsync.ErrNotHeld {
+				var value string
+				var leader *redsync.Metadata
+				if info != nil {
+					value = info.Value
+					leader = info.Metadata
+				}
+				if first || value != last {
+					select {
+					case leaders <- leader:
+					case <-ctx.Done():
+						return
+					}
+					first = false
+					last = value
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return leaders
+}
@@ -0,0 +1,112 @@
+package election_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+	"github.com/rgalanakis/redsync/election"
+	"github.com/rgalanakis/redsync/rstest"
+)
+
+func TestElection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Election Suite")
+}
+
+var tr = make(rstest.TempServers, 3)
+
+var _ = BeforeSuite(func() {
+	tr.Start()
+})
+
+var _ = AfterSuite(func() {
+	tr.Stop()
+})
+
+var _ = Describe("Election", func() {
+	var rs *redsync.Redsync
+
+	BeforeEach(func() {
+		rs = redsync.New(tr.Pools(3)...)
+	})
+
+	newElection := func(name, service string) *election.Election {
+		return election.New(rs, name, election.Opts{
+			Expiry:       150 * time.Millisecond,
+			RetryDelay:   10 * time.Millisecond,
+			PollInterval: 10 * time.Millisecond,
+			Identity:     &redsync.Metadata{Service: service},
+		})
+	}
+
+	It("elects a single leader until it resigns", func() {
+		e1 := newElection("election-resign", "one")
+		e2 := newElection("election-resign", "two")
+		Expect(e1.IsLeader()).To(BeFalse())
+		Expect(e1.Done()).To(BeClosed())
+
+		Expect(e1.Campaign(context.Background())).To(Succeed())
+		Expect(e1.IsLeader()).To(BeTrue())
+		Expect(e1.Campaign(context.Background())).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+		defer cancel()
+		Expect(e2.Campaign(ctx)).To(Equal(context.DeadlineExceeded))
+		Expect(e1.IsLeader()).To(BeTrue())
+
+		leader, err := e2.Leader()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(leader.Service).To(Equal("one"))
+
+		elected := make(chan error)
+		go func() {
+			elected <- e2.Campaign(context.Background())
+		}()
+		done := e1.Done()
+		Expect(e1.Resign()).To(Succeed())
+		Expect(done).To(BeClosed())
+		Expect(e1.IsLeader()).To(BeFalse())
+		Eventually(elected).Should(Receive(BeNil()))
+		Expect(e2.IsLeader()).To(BeTrue())
+
+		leader, err = e1.Leader()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(leader.Service).To(Equal("two"))
+
+		Expect(e2.Resign()).To(Succeed())
+		_, err = e1.Leader()
+		Expect(err).To(Equal(election.ErrNoLeader))
+	})
+
+	It("steps down when the leader lock is lost", func() {
+		e := newElection("election-lost", "one")
+		Expect(e.Campaign(context.Background())).To(Succeed())
+		rs.ForceUnlock("election-lost")
+		Eventually(e.Done()).Should(BeClosed())
+		Expect(e.IsLeader()).To(BeFalse())
+		Expect(e.Resign()).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("observes leadership changes", func() {
+		e1 := newElection("election-observe", "one")
+		e2 := newElection("election-observe", "two")
+		ctx, cancel := context.WithCancel(context.Background())
+		leaders := e2.Observe(ctx)
+		Eventually(leaders).Should(Receive(BeNil()))
+
+		Expect(e1.Campaign(context.Background())).To(Succeed())
+		var leader *redsync.Metadata
+		Eventually(leaders).Should(Receive(&leader))
+		Expect(leader.Service).To(Equal("one"))
+
+		Expect(e1.Resign()).To(Succeed())
+		Eventually(leaders).Should(Receive(BeNil()))
+
+		cancel()
+		Eventually(leaders).Should(BeClosed())
+	})
+})
@@ -3,3 +3,6 @@ package redsync
 import "errors"
 
 var ErrFailed = errors.New("redsync: failed to acquire lock")
+
+// ErrNotHeld is returned when a lock is not held on a quorum of nodes.
+var ErrNotHeld = errors.New("redsync: lock is not held")
@@ -26,14 +26,14 @@ func ExampleMutex_Lock() {
 
 	pools := rstest.PoolsForConn(conn, 1)
 	mutex := redsync.New(pools...).NewMutex("example-mutex-lock", redsync.NonBlocking())
-	err := mutex.Lock()
+	lease, err := mutex.Lock()
 	if err == redsync.ErrFailed {
 		fmt.Println("Failed to acquire lock.")
 	} else if err != nil {
 		fmt.Println("Lock acquisition had unexpected error")
 	} else {
 		fmt.Println("Acquired lock")
-		defer mutex.Unlock()
+		defer lease.Unlock()
 		expensiveOperation()
 	}
 	// Output:
@@ -80,9 +80,9 @@ func Example() {
 	pool := &redis.Pool{Dial: redsync.TcpDialer(host)}
 	mutex := redsync.New(pool).NewMutex("redsync-example", redsync.NonBlocking())
 
-	// Use Mutex#Lock and Mutex#Unlock manually
-	if mutex.Lock() != nil {
-		defer mutex.Unlock()
+	// Use Mutex#Lock and Lease#Unlock manually
+	if lease, err := mutex.Lock(); err == nil {
+		defer lease.Unlock()
 		expensiveOperation()
 	}
 
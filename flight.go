@@ -0,0 +1,144 @@
+package redsync
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// flight runs a function under a lock, and shares its outcome with other processes
+// through a record stored on a quorum of nodes. It is the machinery behind Once and Group.
+type flight struct {
+	rs           *Redsync
+	name         string
+	recordKey    string
+	ttl          time.Duration
+	wait         bool
+	pollInterval time.Duration
+	expiry       time.Duration
+	// remoteErr converts the error message of a function that failed in another process to an error.
+	remoteErr func(msg string) error
+}
+
+// flightRecord is the outcome of a run, stored on each node.
+type flightRecord struct {
+	// Value is the lock value of the run, which makes each record unique.
+	Value string `json:"value"`
+	// Error is the error message of a failed run, or empty for a successful run.
+	Error string `json:"error,omitempty"`
+	// Result is the result of a successful run.
+	Result []byte `json:"result,omitempty"`
+}
+
+// do returns the result of the last successful run if it is recorded,
+// otherwise it runs fn under the lock, or waits for another process running it.
+func (f *flight) do(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	for {
+		rec, err := f.record()
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil && rec.Error == "" {
+			return rec.Result, nil
+		}
+
+		opts := NonBlocking()
+		opts.Expiry = f.expiry
+		lease, err := f.rs.NewMutex(f.name, opts).Lock()
+		if err == nil {
+			return f.run(lease, fn)
+		}
+		if err != ErrFailed {
+			return nil, err
+		}
+		if !f.wait {
+			return nil, ErrInProgress
+		}
+
+		after, err := f.waitFor(ctx, rec)
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			if after.Error != "" {
+				return nil, f.remoteErr(after.Error)
+			}
+			return after.Result, nil
+		}
+		// The other process gave up without recording a result, so try again.
+	}
+}
+
+// run runs fn while holding lease, and records the outcome.
+func (f *flight) run(lease *Lease, fn func() ([]byte, error)) ([]byte, error) {
+	defer lease.Unlock()
+	// Another process may have finished between checking the record and acquiring the lock.
+	rec, err := f.record()
+	if err != nil {
+		return nil, err
+	}
+	if rec != nil && rec.Error == "" {
+		return rec.Result, nil
+	}
+
+	stop := make(chan struct{})
+	stopped := lease.KeepAlive(stop)
+	result, fnErr := fn()
+	close(stop)
+	<-stopped
+
+	rec = &flightRecord{Value: lease.Value()}
+	if fnErr != nil {
+		rec.Error = fnErr.Error()
+	} else {
+		rec.Result = result
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	if !lease.SetIfHeld(f.recordKey, string(b), f.ttl) && fnErr == nil {
+		return result, ErrNotHeld
+	}
+	return result, fnErr
+}
+
+// waitFor waits until the lock is no longer held, and returns the record written since prev.
+// It returns a nil record if there is no new record.
+func (f *flight) waitFor(ctx context.Context, prev *flightRecord) (*flightRecord, error) {
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if _, err := f.rs.Inspect(f.name); err == nil {
+			continue
+		} else if err != ErrNotHeld {
+			return nil, err
+		}
+		rec, err := f.record()
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil || (prev != nil && rec.Value == prev.Value) {
+			return nil, nil
+		}
+		return rec, nil
+	}
+}
+
+// record returns the record of the most recent run, or nil if there is none.
+func (f *flight) record() (*flightRecord, error) {
+	value, err := f.rs.Get(f.recordKey)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	rec := &flightRecord{}
+	if err := json.Unmarshal([]byte(value), rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
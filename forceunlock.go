@@ -0,0 +1,96 @@
+package redsync
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NodeResult is the result of an operation on a single node.
+type NodeResult struct {
+	// Node is the index of the node's pool, in the order the pools were passed to New.
+	Node int
+	// Deleted is true if the lock key was deleted from the node.
+	Deleted bool
+	// Err is the error talking to the node, if any.
+	Err error
+}
+
+// BrokenChannel returns the name of the pub/sub channel that ForceUnlock and ForceUnlockIf
+// publish the value of a deleted lock to.
+func BrokenChannel(name string) string {
+	return "redsync:broken:" + name
+}
+
+// ForceUnlock deletes the lock with the given name from all nodes, regardless of who holds it.
+// It is meant for administrative use, to clear locks that are stuck.
+// The value of the deleted lock is published to BrokenChannel(name),
+// so the former holder can find out its lock was broken (see WatchBroken).
+func (r *Redsync) ForceUnlock(name string) []NodeResult {
+	return r.forceUnlock(name, false, "")
+}
+
+// ForceUnlockIf is like ForceUnlock, but only deletes the lock from nodes where it holds value.
+// Use it to break a specific holder's lock (as reported by Inspect or Status)
+// without the risk of breaking a lock that was acquired by someone else in the meantime.
+func (r *Redsync) ForceUnlockIf(name, value string) []NodeResult {
+	return r.forceUnlock(name, true, value)
+}
+
+var forceDeleteScript = redis.NewScript(1, `
+	local value = redis.call("GET", KEYS[1])
+	if not value then
+		return 0
+	end
+	if ARGV[2] == "1" and value ~= ARGV[3] then
+		return 0
+	end
+	redis.call("DEL", KEYS[1])
+	redis.call("PUBLISH", ARGV[1], value)
+	return 1
+`)
+
+func (r *Redsync) forceUnlock(name string, conditional bool, value string) []NodeResult {
+	cond := "0"
+	if conditional {
+		cond = "1"
+	}
+	results := make([]NodeResult, len(r.pools))
+	for i, pool := range r.pools {
+		conn := pool.Get()
+		deleted, err := redis.Bool(forceDeleteScript.Do(conn, name, BrokenChannel(name), cond, value))
+		conn.Close()
+		results[i] = NodeResult{Node: i, Deleted: deleted, Err: err}
+	}
+	return results
+}
+
+// WatchBroken subscribes to BrokenChannel(name) on all nodes,
+// and returns a channel that receives the value of each lock with the given name
+// that is deleted through ForceUnlock or ForceUnlockIf.
+// Each value is delivered once, even though it is published by every node it was deleted from.
+// A lock holder can compare the received values to Mutex.Value to find out its lock was broken.
+// The returned channel is closed when ctx is done or the subscriptions fail.
+func (r *Redsync) WatchBroken(ctx context.Context, name string) (<-chan string, error) {
+	messages, err := subscribe(ctx, r.pools, BrokenChannel(name))
+	if err != nil {
+		return nil, err
+	}
+	broken := make(chan string)
+	go func() {
+		defer close(broken)
+		seen := make(map[string]bool)
+		for data := range messages {
+			value := string(data)
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			select {
+			case broken <- value:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return broken, nil
+}
@@ -0,0 +1,81 @@
+package redsync_test
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("ForceUnlock", func() {
+	It("deletes the lock from all nodes regardless of owner", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-force-unlock", redsync.NonBlocking())
+		lease := mustLock(mutex)
+
+		results := rs.ForceUnlock("test-force-unlock")
+		Expect(results).To(HaveLen(3))
+		for i, res := range results {
+			Expect(res.Node).To(Equal(i))
+			Expect(res.Deleted).To(BeTrue())
+			Expect(res.Err).ToNot(HaveOccurred())
+		}
+		_, err := rs.Inspect("test-force-unlock")
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+		Expect(lease.Unlock()).To(BeFalse())
+	})
+
+	It("reports nodes that did not hold the lock or failed", func() {
+		pools := append(tr.Pools(1), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		results := redsync.New(pools...).ForceUnlock("test-force-unlock-missing")
+		Expect(results[0].Deleted).To(BeFalse())
+		Expect(results[0].Err).ToNot(HaveOccurred())
+		Expect(results[1].Deleted).To(BeFalse())
+		Expect(results[1].Err).To(MatchError(ContainSubstring("connection refused")))
+	})
+
+	It("only deletes a matching value with ForceUnlockIf", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-force-unlock-if", redsync.NonBlocking())
+		lease := mustLock(mutex)
+
+		for _, res := range rs.ForceUnlockIf("test-force-unlock-if", "someone-else") {
+			Expect(res.Deleted).To(BeFalse())
+		}
+		_, err := rs.Inspect("test-force-unlock-if")
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, res := range rs.ForceUnlockIf("test-force-unlock-if", lease.Value()) {
+			Expect(res.Deleted).To(BeTrue())
+		}
+		_, err = rs.Inspect("test-force-unlock-if")
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("publishes broken locks to watchers", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-force-unlock-watch", redsync.NonBlocking())
+		lease := mustLock(mutex)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		broken, err := rs.WatchBroken(ctx, "test-force-unlock-watch")
+		Expect(err).ToNot(HaveOccurred())
+
+		rs.ForceUnlock("test-force-unlock-watch")
+		Eventually(broken).Should(Receive(Equal(lease.Value())))
+		Consistently(broken, "50ms").ShouldNot(Receive())
+
+		cancel()
+		Eventually(broken).Should(BeClosed())
+	})
+
+	It("errors watching without nodes", func() {
+		_, err := redsync.New().WatchBroken(context.Background(), "test-force-unlock-no-nodes")
+		Expect(err).To(MatchError("redsync: no nodes to subscribe to"))
+	})
+})
@@ -0,0 +1,83 @@
+package redsync
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GroupError is returned from Group.Do when it waited for another process to compute a result,
+// and the computation failed there.
+type GroupError struct {
+	// Key is the key passed to Group.Do.
+	Key string
+	// Message is the error message of the failed function.
+	Message string
+}
+
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("redsync: computing %s failed in another process: %s", e.Key, e.Message)
+}
+
+// Group is a distributed singleflight: concurrent calls to Do with the same key,
+// across all processes, share a single computation of the result.
+// It is useful to avoid cache stampedes on expensive computations.
+// Use Redsync.Group to create one.
+//
+// The first caller takes a lock on the key and computes the result,
+// extending the lock for as long as the computation runs.
+// The result is stored on a quorum of nodes for the TTL of the Group;
+// callers in other processes wait for it instead of computing it again,
+// and callers within the TTL get the stored result.
+// Failed computations are stored for the TTL too, so the callers waiting for them can report them,
+// but callers that come later ignore stored failures and compute the result again,
+// replacing the failure once they succeed.
+type Group struct {
+	rs     *Redsync
+	prefix string
+	ttl    time.Duration
+
+	// PollInterval is how often Do checks whether a result was computed in another process.
+	// Defaults to 50ms.
+	PollInterval time.Duration
+	// Expiry is the expiry of the lock held while computing a result.
+	// The lock is extended while computing, so this only matters if the process dies.
+	// Defaults to 8s.
+	Expiry time.Duration
+}
+
+// Group returns a Group storing results for ttl.
+// The prefix is prepended to each key to get the names of the lock and result keys.
+func (r *Redsync) Group(prefix string, ttl time.Duration) *Group {
+	return &Group{
+		rs:           r,
+		prefix:       prefix,
+		ttl:          ttl,
+		PollInterval: 50 * time.Millisecond,
+		Expiry:       8 * time.Second,
+	}
+}
+
+// Do returns the stored result for key if there is one,
+// otherwise it computes it with fn, or waits for another process computing it.
+// If fn ran in this process, Do returns its result and error.
+// If Do waited for another process, it returns that process's result,
+// or a *GroupError if fn failed there.
+// If fn succeeded but its result could not be stored because the lock was lost,
+// Do returns the result along with ErrNotHeld.
+func (g *Group) Do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	name := g.prefix + key
+	fl := &flight{
+		rs:           g.rs,
+		name:         name,
+		recordKey:    name + ":result",
+		ttl:          g.ttl,
+		wait:         true,
+		pollInterval: g.PollInterval,
+		expiry:       g.Expiry,
+		remoteErr: func(msg string) error {
+			return &GroupError{Key: key, Message: msg}
+		},
+	}
+	return fl.do(ctx, fn)
+}
@@ -0,0 +1,121 @@
+package redsync_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Group", func() {
+	newGroup := func(ttl time.Duration) *redsync.Group {
+		g := redsync.New(tr.Pools(3)...).Group("test-group:", ttl)
+		g.PollInterval = 10 * time.Millisecond
+		return g
+	}
+
+	type result struct {
+		value []byte
+		err   error
+	}
+
+	doAll := func(key string, n int, fn func() ([]byte, error)) []result {
+		results := make([]result, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				value, err := newGroup(time.Minute).Do(context.Background(), key, fn)
+				results[i] = result{value, err}
+			}(i)
+		}
+		wg.Wait()
+		return results
+	}
+
+	It("shares one computation between concurrent callers", func() {
+		var calls int32
+		fn := func() ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(100 * time.Millisecond)
+			return []byte("report"), nil
+		}
+		for _, res := range doAll("shared", 5, fn) {
+			Expect(res.err).ToNot(HaveOccurred())
+			Expect(string(res.value)).To(Equal("report"))
+		}
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+
+		value, err := newGroup(time.Minute).Do(context.Background(), "shared", fn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(value)).To(Equal("report"))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	It("computes again once the result expires", func() {
+		var calls int32
+		fn := func() ([]byte, error) {
+			return []byte{byte(atomic.AddInt32(&calls, 1))}, nil
+		}
+		g := newGroup(50 * time.Millisecond)
+		Expect(g.Do(context.Background(), "expiring", fn)).To(Equal([]byte{1}))
+		Expect(g.Do(context.Background(), "expiring", fn)).To(Equal([]byte{1}))
+		time.Sleep(100 * time.Millisecond)
+		Expect(g.Do(context.Background(), "expiring", fn)).To(Equal([]byte{2}))
+	})
+
+	It("shares errors with waiting callers only", func() {
+		var calls int32
+		fn := func() ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(100 * time.Millisecond)
+			return nil, errors.New("report failed")
+		}
+		groupErrs := 0
+		for _, res := range doAll("failing", 3, fn) {
+			Expect(res.err).To(MatchError(ContainSubstring("report failed")))
+			if ge, ok := res.err.(*redsync.GroupError); ok {
+				Expect(ge.Key).To(Equal("failing"))
+				groupErrs++
+			}
+		}
+		Expect(groupErrs).To(Equal(2))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+
+		_, err := newGroup(time.Minute).Do(context.Background(), "failing", fn)
+		Expect(err).To(MatchError("report failed"))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("stores failures until a later caller succeeds", func() {
+		pools := tr.Pools(3)
+		g := redsync.New(pools...).Group("test-group:", time.Minute)
+		stored := func() string {
+			conn := pools[0].Get()
+			defer conn.Close()
+			v, _ := redis.String(conn.Do("GET", "test-group:stored:result"))
+			return v
+		}
+
+		_, err := g.Do(context.Background(), "stored", func() ([]byte, error) {
+			return nil, errors.New("report failed")
+		})
+		Expect(err).To(MatchError("report failed"))
+		Expect(stored()).To(ContainSubstring("report failed"))
+
+		value, err := g.Do(context.Background(), "stored", func() ([]byte, error) {
+			return []byte("report"), nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(value)).To(Equal("report"))
+		Expect(stored()).ToNot(ContainSubstring("report failed"))
+	})
+})
@@ -0,0 +1,106 @@
+package redsync
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// notHeldReply is the error reply of guarded scripts when the lock is not held.
+const notHeldReply = "NOTHELD redsync: lock is not held"
+
+// ErrTooFewKeys is returned by Lease.Guarded when it is passed fewer values than the script has keys.
+var ErrTooFewKeys = errors.New("redsync: too few keys for guarded script")
+
+// GuardedScript is a Lua script that Lease.Guarded runs only while the lock is held.
+type GuardedScript struct {
+	keyCount int
+	script   *redis.Script
+}
+
+// NewGuardedScript returns a GuardedScript with the given number of keys and Lua source.
+// The source sees only its own KEYS and ARGV, as if it was run with EVAL,
+// and runs after the script checked that the lock key still holds the lease's value.
+func NewGuardedScript(keyCount int, src string) *GuardedScript {
+	return &GuardedScript{
+		keyCount: keyCount,
+		script: redis.NewScript(-1, `
+			local function guarded(KEYS, ARGV)
+				`+src+`
+			end
+			if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+				return redis.error_reply("`+notHeldReply+`")
+			end
+			local keys, args = {}, {}
+			for i = 2, #KEYS do
+				keys[i - 1] = KEYS[i]
+			end
+			for i = 2, #ARGV do
+				args[i - 1] = ARGV[i]
+			end
+			return guarded(keys, args)
+		`),
+	}
+}
+
+// Guarded runs script on pool with the given keys and arguments,
+// after checking atomically that the lock is still held on pool;
+// if it is not, it returns ErrNotHeld and script is not run.
+// keysAndArgs holds the keys of script followed by its arguments; if it has fewer values
+// than script has keys, Guarded returns ErrTooFewKeys.
+// This closes the window between checking that the lock is held and writing
+// data that lives in the same Redis as the lock. pool must be one of the lock's nodes.
+// The reply of the script is returned as from redis.Conn.Do.
+func (l *Lease) Guarded(ctx context.Context, pool *redis.Pool, script *GuardedScript, keysAndArgs ...interface{}) (interface{}, error) {
+	if len(keysAndArgs) < script.keyCount {
+		return nil, ErrTooFewKeys
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	args := make([]interface{}, 0, 3+len(keysAndArgs))
+	args = append(args, 1+script.keyCount, l.key)
+	args = append(args, keysAndArgs[:script.keyCount]...)
+	args = append(args, l.value)
+	args = append(args, keysAndArgs[script.keyCount:]...)
+	reply, err := script.script.Do(conn, args...)
+	if err, ok := err.(redis.Error); ok && strings.HasPrefix(err.Error(), "NOTHELD") {
+		return nil, ErrNotHeld
+	}
+	return reply, err
+}
+
+// Cmd is a Redis command run by Lease.GuardedDo.
+type Cmd struct {
+	Name string
+	Args []interface{}
+}
+
+var guardedDoScript = NewGuardedScript(0, `
+	local replies = {}
+	local i = 1
+	while i <= #ARGV do
+		local n = tonumber(ARGV[i])
+		replies[#replies + 1] = redis.call(unpack(ARGV, i + 1, i + n))
+		i = i + n + 1
+	end
+	return replies
+`)
+
+// GuardedDo runs cmds on pool in a single script, like Guarded, and returns their replies.
+// Keys are passed to the script as arguments, so GuardedDo is not suited for Redis Cluster.
+func (l *Lease) GuardedDo(ctx context.Context, pool *redis.Pool, cmds ...Cmd) ([]interface{}, error) {
+	var args []interface{}
+	for _, cmd := range cmds {
+		args = append(args, 1+len(cmd.Args), cmd.Name)
+		args = append(args, cmd.Args...)
+	}
+	return redis.Values(l.Guarded(ctx, pool, guardedDoScript, args...))
+}
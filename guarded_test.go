@@ -0,0 +1,55 @@
+package redsync_test
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Guarded", func() {
+	incrBy := redsync.NewGuardedScript(1, `return redis.call("INCRBY", KEYS[1], ARGV[1])`)
+
+	It("runs scripts and commands only while the lock is held", func() {
+		pools := tr.Pools(3)
+		lease := mustLock(redsync.New(pools...).NewMutex("test-guarded", redsync.NonBlocking()))
+		ctx := context.Background()
+
+		Expect(redis.Int(lease.Guarded(ctx, pools[0], incrBy, "test-guarded-counter", 5))).To(Equal(5))
+		replies, err := lease.GuardedDo(ctx, pools[0],
+			redsync.Cmd{Name: "SET", Args: []interface{}{"test-guarded-data", "v1"}},
+			redsync.Cmd{Name: "GET", Args: []interface{}{"test-guarded-counter"}},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(redis.Strings(replies, nil)).To(Equal([]string{"OK", "5"}))
+
+		Expect(lease.Unlock()).To(BeTrue())
+		_, err = lease.Guarded(ctx, pools[0], incrBy, "test-guarded-counter", 5)
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+		_, err = lease.GuardedDo(ctx, pools[0], redsync.Cmd{Name: "SET", Args: []interface{}{"test-guarded-data", "v2"}})
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+
+		conn := pools[0].Get()
+		defer conn.Close()
+		Expect(redis.Strings(conn.Do("MGET", "test-guarded-counter", "test-guarded-data"))).To(Equal([]string{"5", "v1"}))
+	})
+
+	It("returns errors of the script", func() {
+		pools := tr.Pools(1)
+		lease := mustLock(redsync.New(pools...).NewMutex("test-guarded-error", redsync.NonBlocking()))
+		defer lease.Unlock()
+		_, err := lease.GuardedDo(context.Background(), pools[0], redsync.Cmd{Name: "NOSUCHCOMMAND"})
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(Equal(redsync.ErrNotHeld))
+	})
+
+	It("errors if fewer values than keys are passed", func() {
+		pools := tr.Pools(1)
+		lease := mustLock(redsync.New(pools...).NewMutex("test-guarded-keys", redsync.NonBlocking()))
+		defer lease.Unlock()
+		_, err := lease.Guarded(context.Background(), pools[0], incrBy)
+		Expect(err).To(Equal(redsync.ErrTooFewKeys))
+	})
+})
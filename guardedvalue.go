@@ -0,0 +1,139 @@
+//go:build go1.18
+// +build go1.18
+
+package redsync
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Guarded is a value of type T, stored as JSON in Redis next to the lock that protects it,
+// such as a small shared config document.
+// The value is stored on every node with a version, and read from the node with the latest version,
+// so a write that reached only some nodes is not lost.
+type Guarded[T any] struct {
+	mutex *Mutex
+	key   string
+}
+
+// NewGuarded returns a Guarded value protected by the lock with the given name and options.
+// The value is stored in the key name+":value".
+func NewGuarded[T any](rs *Redsync, name string, opts MutexOpts) *Guarded[T] {
+	return &Guarded[T]{mutex: rs.NewMutex(name, opts), key: name + ":value"}
+}
+
+var guardedWriteScript = NewGuardedScript(1, `
+	return redis.call("HMSET", KEYS[1], "version", ARGV[1], "data", ARGV[2])
+`)
+
+// Load returns the current value, or the zero value of T if it was never written.
+// It does not lock, so the value may be changed concurrently.
+func (g *Guarded[T]) Load(ctx context.Context) (T, error) {
+	var value T
+	_, data, err := g.load(ctx)
+	if err != nil || data == nil {
+		return value, err
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// Update locks, loads the value, calls fn to modify it, writes it back, and unlocks.
+// The value is only written if fn returns nil, and only on nodes where the lock is still held;
+// if it was not written on a quorum of nodes, Update returns ErrNotHeld.
+// Errors from locking, fn and Redis are returned.
+func (g *Guarded[T]) Update(ctx context.Context, fn func(*T) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lease, err := g.mutex.Lock()
+	if err != nil {
+		return err
+	}
+	defer lease.Unlock()
+
+	version, data, err := g.load(ctx)
+	if err != nil {
+		return err
+	}
+	var value T
+	if data != nil {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+	if err := fn(&value); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(value); err != nil {
+		return err
+	}
+
+	n := 0
+	var firstErr error
+	for _, pool := range g.mutex.pools {
+		_, err := lease.Guarded(ctx, pool, guardedWriteScript, g.key, version+1, data)
+		if err == nil {
+			n++
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if n >= g.mutex.quorum {
+		return nil
+	}
+	if firstErr == nil || firstErr == ErrNotHeld {
+		return ErrNotHeld
+	}
+	return firstErr
+}
+
+// load returns the version and data with the latest version on any node,
+// or nil data if there is none. A quorum of nodes must reply.
+func (g *Guarded[T]) load(ctx context.Context) (int64, []byte, error) {
+	var version int64
+	var data []byte
+	n := 0
+	var firstErr error
+	for _, pool := range g.mutex.pools {
+		v, d, err := loadVersion(ctx, pool, g.key)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		n++
+		if d != nil && v > version {
+			version, data = v, d
+		}
+	}
+	if n < g.mutex.quorum {
+		return 0, nil, firstErr
+	}
+	return version, data, nil
+}
+
+func loadVersion(ctx context.Context, pool *redis.Pool, key string) (int64, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close()
+	reply, err := redis.Values(conn.Do("HMGET", key, "version", "data"))
+	if err != nil || reply[1] == nil {
+		return 0, nil, err
+	}
+	version, err := redis.Int64(reply[0], nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := redis.Bytes(reply[1], nil)
+	return version, data, err
+}
@@ -0,0 +1,72 @@
+//go:build go1.18
+// +build go1.18
+
+package redsync_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Guarded value", func() {
+	type config struct {
+		Version int
+		Hosts   []string
+	}
+
+	It("serializes concurrent updates", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				g := redsync.NewGuarded[config](rs, "test-guarded-value", redsync.Blocking())
+				Expect(g.Update(context.Background(), func(c *config) error {
+					c.Version++
+					c.Hosts = append(c.Hosts, "host")
+					return nil
+				})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		c, err := redsync.NewGuarded[config](rs, "test-guarded-value", redsync.Blocking()).Load(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Version).To(Equal(8))
+		Expect(c.Hosts).To(HaveLen(8))
+	})
+
+	It("loads the latest version from any node", func() {
+		pools := tr.Pools(3)
+		g := redsync.NewGuarded[config](redsync.New(pools...), "test-guarded-latest", redsync.NonBlocking())
+		c, err := g.Load(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(config{}))
+
+		Expect(g.Update(context.Background(), func(c *config) error { c.Version = 1; return nil })).To(Succeed())
+		conn := pools[1].Get()
+		_, err = conn.Do("DEL", "test-guarded-latest:value")
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.Load(context.Background())).To(Equal(config{Version: 1}))
+		Expect(g.Update(context.Background(), func(c *config) error { c.Version++; return nil })).To(Succeed())
+		Expect(g.Load(context.Background())).To(Equal(config{Version: 2}))
+	})
+
+	It("does not write the value if fn fails", func() {
+		g := redsync.NewGuarded[config](redsync.New(tr.Pools(3)...), "test-guarded-fail", redsync.NonBlocking())
+		errBoom := errors.New("boom")
+		Expect(g.Update(context.Background(), func(c *config) error {
+			c.Version = 1
+			return errBoom
+		})).To(Equal(errBoom))
+		Expect(g.Load(context.Background())).To(Equal(config{}))
+	})
+})
@@ -0,0 +1,85 @@
+package redsync
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// leaseToken is the state of a Lease exported by Lease.Export.
+type leaseToken struct {
+	// Kind is "mutex", "multi" or "path".
+	Kind   string        `json:"kind"`
+	Names  []string      `json:"names"`
+	Value  string        `json:"value"`
+	Until  time.Time     `json:"until"`
+	Expiry time.Duration `json:"expiry"`
+	Factor float64       `json:"factor"`
+	// Nodes is the number of nodes the lock is held on.
+	Nodes int `json:"nodes"`
+}
+
+// Export returns a token with the state of the lease, so another process can take over the lock
+// with Redsync.Adopt, without a window in which the lock is free.
+// Export detaches the lease from this process: its KeepAlive is stopped,
+// and it is removed from the Redsync, so Redsync.Close and ReleaseAll do not unlock it.
+// After handing the token over, the lease should no longer be extended or unlocked.
+func (l *Lease) Export() string {
+	if l.stop() && l.queue != nil {
+		l.mutex.queues.pass(l.name, l.queue, l, nil)
+	}
+	t := leaseToken{
+		Value:  l.value,
+		Until:  l.Until(),
+		Expiry: l.mutex.expiry,
+		Factor: l.mutex.factor,
+		Nodes:  len(l.mutex.pools),
+	}
+	switch locker := l.locker.(type) {
+	case *MultiMutex:
+		t.Kind, t.Names = "multi", locker.names
+	case *PathMutex:
+		t.Kind, t.Names = "path", []string{locker.path}
+	default:
+		t.Kind, t.Names = "mutex", []string{l.name}
+	}
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ErrInvalidToken is returned from Adopt when a token was not created by Lease.Export,
+// or was created for a different number of nodes.
+var ErrInvalidToken = errors.New("redsync: invalid lease token")
+
+// Adopt takes over the lock of a lease exported with Lease.Export, usually in another process,
+// and returns a Lease that holds it. The Redsync must use the same nodes, in the same order,
+// as the Redsync the lease was acquired with.
+// The lock is extended on a quorum of nodes before it is returned;
+// if it cannot be, because the lock has expired or was lost, Adopt returns ErrNotHeld.
+func (r *Redsync) Adopt(token string) (*Lease, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var t leaseToken
+	if err := json.Unmarshal(b, &t); err != nil || len(t.Names) == 0 || t.Nodes != len(r.pools) {
+		return nil, ErrInvalidToken
+	}
+	if !time.Now().Before(t.Until) {
+		return nil, ErrNotHeld
+	}
+	opts := NonBlocking()
+	opts.Expiry = t.Expiry
+	opts.Factor = t.Factor
+
+	switch t.Kind {
+	case "mutex":
+		return r.NewMutex(t.Names[0], opts).Reclaim(t.Value)
+	case "multi":
+		return r.NewMultiMutex(t.Names, opts).Reclaim(t.Value)
+	case "path":
+		return r.NewPathMutex(t.Names[0], opts).Reclaim(t.Value)
+	}
+	return nil, ErrInvalidToken
+}
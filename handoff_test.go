@@ -0,0 +1,72 @@
+package redsync_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Handoff", func() {
+	It("hands a lock over without releasing it", func() {
+		pools := tr.Pools(3)
+		lease := mustLock(redsync.New(pools...).NewMutex("test-handoff", redsync.NonBlocking()))
+		token := lease.Export()
+
+		adopted, err := redsync.New(pools...).Adopt(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(adopted.Name()).To(Equal("test-handoff"))
+		Expect(adopted.Value()).To(Equal(lease.Value()))
+		Expect(adopted.IsHeld(context.Background())).To(BeTrue())
+		Expect(lockErr(redsync.New(pools...).NewMutex("test-handoff", redsync.NonBlocking()))).To(Equal(redsync.ErrFailed))
+
+		Expect(adopted.Extend()).To(BeTrue())
+		Expect(adopted.Unlock()).To(BeTrue())
+		_, err = redsync.New(pools...).Adopt(token)
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("detaches an exported lease from the exporting Redsync", func() {
+		pools := tr.Pools(3)
+		exporter := redsync.New(pools...)
+		lease := mustLock(exporter.NewMutex("test-handoff-detach", redsync.NonBlocking()))
+		stopped := lease.KeepAlive(make(chan struct{}))
+		token := lease.Export()
+		Eventually(stopped).Should(BeClosed())
+		Expect(exporter.Held()).To(BeEmpty())
+
+		adopted, err := redsync.New(pools...).Adopt(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exporter.Close(context.Background())).To(Succeed())
+		Expect(adopted.IsHeld(context.Background())).To(BeTrue())
+		Expect(adopted.Unlock()).To(BeTrue())
+	})
+
+	It("hands over multi and path locks", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		multi := mustLock(rs.NewMultiMutex([]string{"test-handoff-b", "test-handoff-a"}, redsync.NonBlocking()))
+		path := mustLock(rs.NewPathMutex("test-handoff/x/y", redsync.NonBlocking()))
+
+		for _, lease := range []*redsync.Lease{multi, path} {
+			adopted, err := redsync.New(pools...).Adopt(lease.Export())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adopted.Name()).To(Equal(lease.Name()))
+			Expect(adopted.IsHeld(context.Background())).To(BeTrue())
+			Expect(adopted.Unlock()).To(BeTrue())
+		}
+		Expect(mustLock(rs.NewPathMutex("test-handoff", redsync.NonBlocking())).Unlock()).To(BeTrue())
+	})
+
+	It("rejects invalid tokens", func() {
+		pools := tr.Pools(3)
+		lease := mustLock(redsync.New(pools...).NewMutex("test-handoff-invalid", redsync.NonBlocking()))
+		defer lease.Unlock()
+
+		_, err := redsync.New(pools...).Adopt("not a token")
+		Expect(err).To(Equal(redsync.ErrInvalidToken))
+		_, err = redsync.New(pools[:2]...).Adopt(lease.Export())
+		Expect(err).To(Equal(redsync.ErrInvalidToken))
+	})
+})
@@ -0,0 +1,29 @@
+package redsync
+
+import (
+	"time"
+)
+
+// NodeHealth is the result of pinging a single node.
+type NodeHealth struct {
+	// Node is the index of the node's pool, in the order the pools were passed to New.
+	Node int
+	// Latency is the round-trip time of the PING.
+	Latency time.Duration
+	// Err is the error talking to the node, if any.
+	Err error
+}
+
+// Ping sends a PING to every node and reports the result.
+// A lock can only be acquired while a quorum of nodes are healthy.
+func (r *Redsync) Ping() []NodeHealth {
+	health := make([]NodeHealth, len(r.pools))
+	for i, pool := range r.pools {
+		start := time.Now()
+		conn := pool.Get()
+		_, err := conn.Do("PING")
+		conn.Close()
+		health[i] = NodeHealth{Node: i, Latency: time.Since(start), Err: err}
+	}
+	return health
+}
@@ -0,0 +1,118 @@
+package redsync
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// LockInfo describes the holder of a lock, as agreed on by a quorum of nodes.
+type LockInfo struct {
+	// Name is the name of the lock.
+	Name string
+	// Value is the raw value stored in the lock key.
+	Value string
+	// Metadata is the owner metadata stored with the lock, or nil if the holder did not attach any.
+	Metadata *Metadata
+	// TTL is the smallest remaining time-to-live of the lock key among the nodes holding Value.
+	TTL time.Duration
+	// Nodes is the number of nodes holding Value.
+	Nodes int
+	// Signer is who signed the lock value for this lock's name, as verified by Redsync.ValueVerifier.
+	// It is nil if there is no ValueVerifier, or verification failed.
+	// With HMACValueVerifier, the signature also covers Metadata.
+	Signer *Signer
+	// SignerErr is the error verifying the lock token, such as ErrBadSignature.
+	SignerErr error
+}
+
+// Inspect returns information about the current holder of the lock with the given name.
+// The value must be held by a quorum of nodes; if it is not, Inspect returns ErrNotHeld.
+// Nodes that fail to reply are counted as not holding the lock,
+// but if that causes the quorum to be missed, the first node error is returned instead.
+func (r *Redsync) Inspect(name string) (*LockInfo, error) {
+	counts := make(map[string]int)
+	ttls := make(map[string]time.Duration)
+	var firstErr error
+	for _, pool := range r.pools {
+		value, ttl, err := inspectNode(pool, name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		counts[value]++
+		if prev, ok := ttls[value]; !ok || ttl < prev {
+			ttls[value] = ttl
+		}
+	}
+	quorum := Quorum(len(r.pools))
+	for value, n := range counts {
+		if n >= quorum {
+			_, md := decodeValue(value)
+			info := &LockInfo{Name: name, Value: value, Metadata: md, TTL: ttls[value], Nodes: n}
+			if r.ValueVerifier != nil {
+				info.Signer, info.SignerErr = r.ValueVerifier(name, value)
+			}
+			return info, nil
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrNotHeld
+}
+
+// NodeInfo is the state of a lock key on a single node.
+type NodeInfo struct {
+	// Node is the index of the node's pool, in the order the pools were passed to New.
+	Node int
+	// Value is the value stored in the lock key, or empty if the key does not exist.
+	Value string
+	// Metadata is the owner metadata stored with Value, or nil if there is none.
+	Metadata *Metadata
+	// TTL is the remaining time-to-live of the lock key.
+	TTL time.Duration
+	// Err is the error talking to the node, if any.
+	Err error
+}
+
+// InspectNodes returns the state of the lock with the given name on each node,
+// without applying any quorum rules.
+func (r *Redsync) InspectNodes(name string) []NodeInfo {
+	infos := make([]NodeInfo, len(r.pools))
+	for i, pool := range r.pools {
+		value, ttl, err := inspectNode(pool, name)
+		_, md := decodeValue(value)
+		infos[i] = NodeInfo{Node: i, Value: value, Metadata: md, TTL: ttl, Err: err}
+	}
+	return infos
+}
+
+var inspectScript = redis.NewScript(1, `
+	return {redis.call("GET", KEYS[1]), redis.call("PTTL", KEYS[1])}
+`)
+
+// inspectNode returns the value and remaining TTL of the key on a single node.
+// The value is empty if the key does not exist.
+func inspectNode(pool *redis.Pool, name string) (string, time.Duration, error) {
+	conn := pool.Get()
+	defer conn.Close()
+	reply, err := redis.Values(inspectScript.Do(conn, name))
+	if err != nil {
+		return "", 0, err
+	}
+	var value string
+	var pttl int64
+	if _, err := redis.Scan(reply, &value, &pttl); err != nil {
+		return "", 0, err
+	}
+	if pttl < 0 {
+		pttl = 0
+	}
+	return value, time.Duration(pttl) * time.Millisecond, nil
+}
@@ -0,0 +1,191 @@
+package redsync_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Inspect", func() {
+	It("returns the metadata and TTL of the lock holder", func() {
+		pools := tr.Pools(4)
+		rs := redsync.New(pools...)
+		opts := redsync.NonBlocking()
+		opts.Metadata = redsync.ProcessMetadata("inspector")
+		opts.Metadata.RequestID = "req-1"
+		opts.Metadata.Labels = map[string]string{"job": "backfill"}
+		mutex := rs.NewMutex("test-inspect", opts)
+
+		before := time.Now()
+		lease := mustLock(mutex)
+		defer lease.Unlock()
+
+		info, err := rs.Inspect("test-inspect")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Name).To(Equal("test-inspect"))
+		Expect(info.Value).To(Equal(lease.Value()))
+		Expect(info.Nodes).To(Equal(4))
+		Expect(info.TTL).To(BeNumerically(">", 0))
+		Expect(info.TTL).To(BeNumerically("<=", opts.Expiry))
+		Expect(info.Metadata).ToNot(BeNil())
+		Expect(info.Metadata.Service).To(Equal("inspector"))
+		Expect(info.Metadata.RequestID).To(Equal("req-1"))
+		Expect(info.Metadata.PID).To(Equal(opts.Metadata.PID))
+		Expect(info.Metadata.Hostname).To(Equal(opts.Metadata.Hostname))
+		Expect(info.Metadata.Labels).To(HaveKeyWithValue("job", "backfill"))
+		Expect(info.Metadata.AcquiredAt).To(BeTemporally("~", before, time.Second))
+		Expect(opts.Metadata.AcquiredAt.IsZero()).To(BeTrue())
+	})
+
+	It("verifies signed lock tokens", func() {
+		pools := tr.Pools(3)
+		key := []byte("secret")
+		rs := redsync.New(pools...)
+		rs.ValueGenerator = redsync.HMACValueGenerator(key, "billing")
+		rs.ValueVerifier = redsync.HMACValueVerifier(key)
+		opts := redsync.NonBlocking()
+		opts.Metadata = redsync.ProcessMetadata("billing")
+
+		before := time.Now()
+		lease := mustLock(rs.NewMutex("test-inspect-signed", opts))
+		defer lease.Unlock()
+		info, err := rs.Inspect("test-inspect-signed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.SignerErr).ToNot(HaveOccurred())
+		Expect(info.Signer.ID).To(Equal("billing"))
+		Expect(info.Signer.SignedAt).To(BeTemporally("~", before, time.Second))
+
+		forged := redsync.New(pools...)
+		forged.ValueGenerator = redsync.HMACValueGenerator([]byte("guess"), "billing")
+		lease = mustLock(forged.NewMutex("test-inspect-forged", redsync.NonBlocking()))
+		defer lease.Unlock()
+		info, err = rs.Inspect("test-inspect-forged")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Signer).To(BeNil())
+		Expect(info.SignerErr).To(Equal(redsync.ErrBadSignature))
+	})
+
+	It("does not verify signed values stored under another name or with other metadata", func() {
+		pools := tr.Pools(3)
+		key := []byte("secret")
+		rs := redsync.New(pools...)
+		rs.ValueGenerator = redsync.HMACValueGenerator(key, "billing")
+		rs.ValueVerifier = redsync.HMACValueVerifier(key)
+		opts := redsync.NonBlocking()
+		opts.Metadata = redsync.ProcessMetadata("billing")
+		lease := mustLock(rs.NewMutex("test-inspect-signed-copy", opts))
+		defer lease.Unlock()
+
+		set := func(name, value string) {
+			for _, pool := range pools {
+				conn := pool.Get()
+				_, err := conn.Do("SET", name, value, "PX", 10000)
+				conn.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}
+		}
+		set("test-inspect-signed-copied", lease.Value())
+		info, err := rs.Inspect("test-inspect-signed-copied")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Signer).To(BeNil())
+		Expect(info.SignerErr).To(Equal(redsync.ErrBadSignature))
+
+		set("test-inspect-signed-copy", strings.Replace(lease.Value(), `"service":"billing"`, `"service":"payroll"`, 1))
+		info, err = rs.Inspect("test-inspect-signed-copy")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Metadata.Service).To(Equal("payroll"))
+		Expect(info.SignerErr).To(Equal(redsync.ErrBadSignature))
+	})
+
+	It("verifies signed tokens of a MultiMutex under each of its names", func() {
+		key := []byte("secret")
+		rs := redsync.New(tr.Pools(3)...)
+		rs.ValueGenerator = redsync.HMACValueGenerator(key, "billing")
+		rs.ValueVerifier = redsync.HMACValueVerifier(key)
+		names := []string{"test-inspect-multi-b", "test-inspect-multi-a"}
+		lease := mustLock(rs.NewMultiMutex(names, redsync.NonBlocking()))
+		defer lease.Unlock()
+		for _, name := range names {
+			info, err := rs.Inspect(name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.SignerErr).ToNot(HaveOccurred(), name)
+			Expect(info.Signer.ID).To(Equal("billing"))
+		}
+	})
+
+	It("uses the value generator of the mutex options", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		rs.ValueGenerator = redsync.HMACValueGenerator([]byte("secret"), "billing")
+		opts := redsync.NonBlocking()
+		opts.ValueGenerator = func([]string, *redsync.Metadata) (string, error) { return "fixed", nil }
+		lease := mustLock(rs.NewMutex("test-inspect-fixed", opts))
+		defer lease.Unlock()
+		Expect(lease.Value()).To(Equal("fixed"))
+	})
+
+	It("returns nil metadata for locks acquired without it", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-inspect-plain", redsync.NonBlocking())
+		lease := mustLock(mutex)
+		defer lease.Unlock()
+
+		info, err := rs.Inspect("test-inspect-plain")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Value).To(Equal(lease.Value()))
+		Expect(info.Metadata).To(BeNil())
+	})
+
+	It("ignores values held on only a minority of nodes", func() {
+		pools := tr.Pools(3)
+		conn := pools[0].Get()
+		_, err := conn.Do("SET", "test-inspect-minority", "foobar", "PX", 10000)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = redsync.New(pools...).Inspect("test-inspect-minority")
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("returns ErrNotHeld for unlocked mutexes", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-inspect-unlocked", redsync.NonBlocking())
+		lease := mustLock(mutex)
+		Expect(lease.Unlock()).To(BeTrue())
+
+		_, err := rs.Inspect("test-inspect-unlocked")
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("returns the node error if a quorum could not be reached", func() {
+		pools := []*redis.Pool{{Dial: redsync.TcpDialer("127.0.0.1:1")}}
+		_, err := redsync.New(pools...).Inspect("test-inspect-error")
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	})
+})
+
+var _ = Describe("InspectNodes", func() {
+	It("returns the state of the lock on every node", func() {
+		pools := append(tr.Pools(2), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		rs := redsync.New(pools...)
+		conn := pools[1].Get()
+		_, err := conn.Do("SET", "test-inspect-nodes", "foobar", "PX", 10000)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		infos := rs.InspectNodes("test-inspect-nodes")
+		Expect(infos).To(HaveLen(3))
+		Expect(infos[0].Value).To(BeEmpty())
+		Expect(infos[0].Err).ToNot(HaveOccurred())
+		Expect(infos[1].Node).To(Equal(1))
+		Expect(infos[1].Value).To(Equal("foobar"))
+		Expect(infos[1].Metadata).To(BeNil())
+		Expect(infos[1].TTL).To(BeNumerically(">", 0))
+		Expect(infos[2].Err).To(MatchError(ContainSubstring("connection refused")))
+	})
+})
@@ -0,0 +1,152 @@
+package redsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// CountDownLatch lets processes wait until a number of events have happened in any process.
+// It is like Java's CountDownLatch, but distributed.
+// Use Redsync.CountDownLatch to create one.
+//
+// The count is kept in a counter on every node, and decremented atomically.
+// The latch is open once the counter reached zero on a quorum of nodes.
+// Waiters are woken up using pub/sub, and also poll, in case a notification was missed.
+// A latch cannot be reset; once open, it stays open until its key expires,
+// so use a new name for each use, like "deploy-1234-migrated".
+type CountDownLatch struct {
+	rs    *Redsync
+	name  string
+	count int
+
+	// TTL is how long the latch's counters are kept after the first CountDown. Defaults to 24h.
+	TTL time.Duration
+	// PollInterval is how often Wait checks the counters, in addition to being notified.
+	// Defaults to 1s.
+	PollInterval time.Duration
+}
+
+// CountDownLatch returns a CountDownLatch with the given name,
+// that opens after CountDown has been called count times.
+// All processes must use the same count for the same name.
+// Like Java's CountDownLatch, a latch with a count of zero or less is open from the start.
+func (r *Redsync) CountDownLatch(name string, count int) *CountDownLatch {
+	return &CountDownLatch{
+		rs:           r,
+		name:         name,
+		count:        count,
+		TTL:          24 * time.Hour,
+		PollInterval: time.Second,
+	}
+}
+
+var countDownScript = redis.NewScript(1, `
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	end
+	local n = redis.call("DECR", KEYS[1])
+	if n <= 0 then
+		redis.call("PUBLISH", ARGV[3], n)
+	end
+	return n
+`)
+
+// CountDown decrements the count of the latch.
+// It returns ErrFailed if the count could not be decremented on a quorum of nodes,
+// in which case it should not be retried, since it may have been counted on some nodes.
+func (l *CountDownLatch) CountDown() error {
+	n := 0
+	var firstErr error
+	for _, pool := range l.rs.pools {
+		conn := pool.Get()
+		_, err := countDownScript.Do(conn, l.name, l.count, int(l.TTL/time.Millisecond), l.channel())
+		conn.Close()
+		if err == nil {
+			n++
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if n < Quorum(len(l.rs.pools)) {
+		if firstErr != nil {
+			return firstErr
+		}
+		return ErrFailed
+	}
+	return nil
+}
+
+// Wait blocks until the latch is open, or ctx is done.
+func (l *CountDownLatch) Wait(ctx context.Context) error {
+	if l.count <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Subscribe before checking, so an opening in between is not missed.
+	notified, err := subscribe(ctx, l.rs.pools, l.channel())
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(l.PollInterval)
+	defer ticker.Stop()
+	for {
+		if l.isOpen() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-notified:
+			if !ok {
+				// All subscriptions failed; keep polling.
+				notified = nil
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// isOpen returns true if the counter reached zero on a quorum of nodes.
+func (l *CountDownLatch) isOpen() bool {
+	n := 0
+	for _, pool := range l.rs.pools {
+		conn := pool.Get()
+		count, err := redis.Int(conn.Do("GET", l.name))
+		conn.Close()
+		if err == nil && count <= 0 {
+			n++
+		}
+	}
+	return n >= Quorum(len(l.rs.pools))
+}
+
+func (l *CountDownLatch) channel() string {
+	return "redsync:latch:" + l.name
+}
+
+// Barrier lets a number of parties, in any processes, wait until all of them have arrived.
+// Use Redsync.Barrier to create one.
+//
+// A Barrier is a CountDownLatch where each party counts down when it arrives,
+// so like a CountDownLatch, it cannot be reused; use a new name for each use.
+type Barrier struct {
+	*CountDownLatch
+}
+
+// Barrier returns a Barrier with the given name for the given number of parties.
+// All processes must use the same number of parties for the same name.
+func (r *Redsync) Barrier(name string, parties int) *Barrier {
+	return &Barrier{CountDownLatch: r.CountDownLatch(name, parties)}
+}
+
+// Wait arrives at the barrier, and blocks until all parties have arrived or ctx is done.
+// A party that arrived is counted even if ctx is done before the others arrive.
+func (b *Barrier) Wait(ctx context.Context) error {
+	if err := b.CountDown(); err != nil {
+		return err
+	}
+	return b.CountDownLatch.Wait(ctx)
+}
@@ -0,0 +1,119 @@
+package redsync_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("CountDownLatch", func() {
+	It("opens after being counted down count times", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		latch := rs.CountDownLatch("test-latch", 2)
+		latch.PollInterval = time.Minute
+
+		opened := make(chan error, 1)
+		go func() {
+			opened <- latch.Wait(context.Background())
+		}()
+		Expect(latch.CountDown()).To(Succeed())
+		Consistently(opened, 100*time.Millisecond).ShouldNot(Receive())
+
+		// Count down from another process, to check waiters are notified.
+		Expect(redsync.New(tr.Pools(3)...).CountDownLatch("test-latch", 2).CountDown()).To(Succeed())
+		Eventually(opened).Should(Receive(BeNil()))
+		Expect(latch.Wait(context.Background())).To(Succeed())
+	})
+
+	It("tolerates a minority of failed nodes", func() {
+		pools := append(tr.Pools(2), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		latch := redsync.New(pools...).CountDownLatch("test-latch-node-down", 1)
+		latch.PollInterval = time.Minute
+
+		opened := make(chan error, 1)
+		go func() {
+			opened <- latch.Wait(context.Background())
+		}()
+		Consistently(opened, 100*time.Millisecond).ShouldNot(Receive())
+		Expect(latch.CountDown()).To(Succeed())
+		Eventually(opened).Should(Receive(BeNil()))
+
+		down := &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")}
+		err := redsync.New(tr.Pools(1)[0], down, down).CountDownLatch("test-latch-nodes-down", 1).Wait(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	})
+
+	It("is open from the start with a count of zero", func() {
+		latch := redsync.New(tr.Pools(3)...).CountDownLatch("test-latch-zero", 0)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(latch.Wait(ctx)).To(Succeed())
+	})
+
+	It("returns when ctx is done", func() {
+		latch := redsync.New(tr.Pools(3)...).CountDownLatch("test-latch-ctx", 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(latch.Wait(ctx)).To(Equal(context.DeadlineExceeded))
+	})
+
+	It("opens when the count reaches zero on a quorum of nodes", func() {
+		pools := tr.Pools(3)
+		conn := pools[2].Get()
+		_, err := conn.Do("SET", "test-latch-quorum", 5)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		latch := redsync.New(pools...).CountDownLatch("test-latch-quorum", 1)
+		Expect(latch.CountDown()).To(Succeed())
+		Expect(latch.Wait(context.Background())).To(Succeed())
+	})
+
+	It("fails to count down without a quorum", func() {
+		pools := append(tr.Pools(1), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		latch := redsync.New(pools...).CountDownLatch("test-latch-error", 1)
+		Expect(latch.CountDown()).To(MatchError(ContainSubstring("connection refused")))
+	})
+})
+
+var _ = Describe("Barrier", func() {
+	It("releases all parties once they have arrived", func() {
+		const parties = 4
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var arrived []time.Time
+		var released []time.Time
+		for i := 0; i < parties; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				time.Sleep(time.Duration(i) * 20 * time.Millisecond)
+				mu.Lock()
+				arrived = append(arrived, time.Now())
+				mu.Unlock()
+				barrier := redsync.New(tr.Pools(3)...).Barrier("test-barrier", parties)
+				Expect(barrier.Wait(context.Background())).To(Succeed())
+				mu.Lock()
+				released = append(released, time.Now())
+				mu.Unlock()
+			}(i)
+		}
+		wg.Wait()
+
+		last := arrived[0]
+		for _, t := range arrived {
+			if t.After(last) {
+				last = t
+			}
+		}
+		for _, t := range released {
+			Expect(t).To(BeTemporally(">=", last))
+		}
+	})
+})
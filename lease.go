@@ -0,0 +1,222 @@
+package redsync
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Lease is a held lock, returned from Mutex.Lock.
+// It owns the random value stored in the lock key, which proves it holds the lock.
+// A Lease is goroutine-safe; for example, it can be extended by KeepAlive while it is used elsewhere.
+type Lease struct {
+	mutex  *Mutex
+	locker locker
+	name   string
+	// key is the lock key that holds value; for locks on several keys, the first of them.
+	key   string
+	value string
+
+	mu    sync.Mutex
+	until time.Time
+	// handedOff is true once the lock was handed to the next goroutine in the local queue.
+	handedOff bool
+
+	// done is closed when the lease is unlocked, to stop KeepAlive.
+	done chan struct{}
+	end  sync.Once
+	// queue is the local queue whose turn the lease holds, if the mutex has a LocalPolicy.
+	queue *localQueue
+}
+
+// Name returns the name of the lock.
+func (l *Lease) Name() string {
+	return l.name
+}
+
+// Value returns the value stored in the lock key.
+// If the mutex was created with Metadata, the value includes the encoded metadata.
+func (l *Lease) Value() string {
+	return l.value
+}
+
+// Until returns the time until which the lock is valid, as computed locally
+// when it was acquired or last extended, accounting for clock drift.
+func (l *Lease) Until() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.until
+}
+
+// Remaining returns the time left until Until, or zero if the lock is no longer valid.
+func (l *Lease) Remaining() time.Duration {
+	if d := time.Until(l.Until()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Valid returns true if the lock is still valid, according to Until.
+// It is computed locally, so it is cheap, but it cannot tell if the lock was deleted or broken;
+// use IsHeld for that.
+func (l *Lease) Valid() bool {
+	return time.Now().Before(l.Until())
+}
+
+// IsHeld verifies that the lock still holds the lease's value on a quorum of nodes,
+// for example before committing side effects.
+// If the value is not held on a quorum of nodes and some nodes failed to reply,
+// the first error is returned, since the lock may still be held.
+func (l *Lease) IsHeld(ctx context.Context) (bool, error) {
+	n := 0
+	var firstErr error
+	for _, pool := range l.mutex.pools {
+		ok, err := l.heldOn(ctx, pool)
+		if ok {
+			n++
+		} else if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if n >= l.mutex.quorum {
+		return true, nil
+	}
+	return false, firstErr
+}
+
+func (l *Lease) heldOn(ctx context.Context, pool *redis.Pool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	return l.locker.held(conn, l.value)
+}
+
+// Unlock unlocks the lock and returns the status of unlock.
+// It also stops any KeepAlive of the lease.
+// With LocalHandoff, the lock may be handed to another goroutine instead of being released.
+func (l *Lease) Unlock() bool {
+	first := l.stop()
+	if first && l.queue != nil && l.handoff() {
+		return true
+	}
+	if l.isHandedOff() {
+		return false
+	}
+	released := l.releaseAll() >= l.mutex.quorum
+	if first && l.queue != nil {
+		l.mutex.queues.pass(l.name, l.queue, l, nil)
+	}
+	return released
+}
+
+// stop stops any KeepAlive of the lease and removes it from the registry,
+// and returns true the first time it is called.
+func (l *Lease) stop() bool {
+	first := false
+	l.end.Do(func() {
+		first = true
+		close(l.done)
+		l.mutex.registry.remove(l)
+	})
+	return first
+}
+
+// Extend resets the expiry of the lock to the full Expiry from now,
+// so a long-running operation can keep holding the lock.
+// It returns true if the lock was extended on a quorum of nodes before the lock expired.
+// If Extend returns false, the lock should be considered lost.
+func (l *Lease) Extend() bool {
+	start := time.Now()
+	extended := l.extendAll()
+	until := l.mutex.validUntil(start)
+	if extended >= l.mutex.quorum && time.Now().Before(until) {
+		l.setUntil(until)
+		return true
+	}
+	return false
+}
+
+func (l *Lease) setUntil(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.until = until
+}
+
+// lose marks the lock as lost, so it is no longer Valid.
+func (l *Lease) lose() {
+	l.setUntil(time.Time{})
+}
+
+// KeepAlive extends the lock every third of its Expiry in a new goroutine,
+// until stop is closed, the lease is unlocked, or an extension fails.
+// The returned channel is closed when the goroutine exits.
+func (l *Lease) KeepAlive(stop <-chan struct{}) <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(l.mutex.expiry / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-l.done:
+				return
+			case <-ticker.C:
+				if !l.Extend() {
+					return
+				}
+			}
+		}
+	}()
+	return stopped
+}
+
+// SetIfHeld sets key to value with the given ttl, on every node where the lock is still held.
+// The check and the write happen atomically on each node,
+// so nothing is written after the lock was lost.
+// It returns true if the key was set on a quorum of nodes.
+func (l *Lease) SetIfHeld(key, value string, ttl time.Duration) bool {
+	return fencedSet(l, key, value, ttl) >= l.mutex.quorum
+}
+
+func (l *Lease) acquireAll() (int, error) {
+	n := 0
+	for _, pool := range l.mutex.pools {
+		ok, err := l.locker.acquire(pool, l.value)
+		if ok {
+			n++
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (l *Lease) releaseAll() int {
+	n := 0
+	for _, pool := range l.mutex.pools {
+		if l.locker.release(pool, l.value) {
+			n++
+		}
+	}
+	return n
+}
+
+func (l *Lease) extendAll() int {
+	n := 0
+	for _, pool := range l.mutex.pools {
+		if l.locker.extend(pool, l.value) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,259 @@
+package redsync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// LocalPolicy controls how goroutines of a process that lock the same name through the same Redsync
+// coordinate before going to Redis. See MutexOpts.Local.
+type LocalPolicy int
+
+const (
+	// LocalNone lets every goroutine compete for the lock in Redis. It is the default.
+	LocalNone LocalPolicy = iota
+	// LocalQueue queues goroutines locking the same name, so only one of them at a time
+	// competes for the lock in Redis or holds it. When it unlocks, the lock is released,
+	// and the next goroutine in the queue competes for it with other processes.
+	LocalQueue
+	// LocalHandoff queues goroutines like LocalQueue, but when the holder of a Mutex lock unlocks
+	// while others are queued, the lock is handed to the next of them without being released in Redis.
+	// The next goroutine moves the lock to its own value, with its own Metadata and Expiry.
+	// Other processes cannot get the lock while goroutines of this process are queued for it,
+	// so use it only where that is acceptable.
+	LocalHandoff
+)
+
+// localQueues are the queues of goroutines locking names through a Redsync.
+type localQueues struct {
+	mu     sync.Mutex
+	queues map[string]*localQueue
+}
+
+func newLocalQueues() *localQueues {
+	return &localQueues{queues: make(map[string]*localQueue)}
+}
+
+// localQueue is the queue of goroutines locking a name. Its fields are guarded by localQueues.mu.
+type localQueue struct {
+	// turn holds a value when the next goroutine may go: nil to compete for the lock in Redis,
+	// or the Lease of the previous holder, which handed off the lock without releasing it.
+	turn    chan *Lease
+	waiters int
+	// holder is the lease of the goroutine whose turn it is, or nil while it competes for the lock.
+	holder *Lease
+}
+
+// enter waits at most timeout for the turn to lock name, and returns its queue,
+// and the lease handed off by the previous holder, if any. ok is false if the wait timed out.
+// If the holder's lease expires without being unlocked, its turn is taken over.
+func (qs *localQueues) enter(name string, timeout time.Duration) (q *localQueue, handed *Lease, ok bool) {
+	qs.mu.Lock()
+	q = qs.queues[name]
+	if q == nil {
+		q = &localQueue{turn: make(chan *Lease, 1)}
+		q.turn <- nil
+		qs.queues[name] = q
+	}
+	q.waiters++
+	qs.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		wait := time.Until(deadline)
+		qs.mu.Lock()
+		if q.holder != nil {
+			if d := q.holder.Remaining(); d < wait {
+				wait = d
+			}
+		}
+		qs.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case handed = <-q.turn:
+			timer.Stop()
+			qs.mu.Lock()
+			q.waiters--
+			qs.mu.Unlock()
+			return q, handed, true
+		case <-timer.C:
+		}
+
+		qs.mu.Lock()
+		select {
+		case handed = <-q.turn:
+			q.waiters--
+			qs.mu.Unlock()
+			return q, handed, true
+		default:
+		}
+		if q.holder != nil && !q.holder.Valid() {
+			q.holder = nil
+			q.waiters--
+			qs.mu.Unlock()
+			return q, nil, true
+		}
+		if !time.Now().Before(deadline) {
+			q.waiters--
+			qs.mu.Unlock()
+			return nil, nil, false
+		}
+		qs.mu.Unlock()
+	}
+}
+
+// hold records l as the holder of the turn of q, or nil while competing for the lock.
+func (qs *localQueues) hold(q *localQueue, l *Lease) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	q.holder = l
+}
+
+// waiting returns true if goroutines are waiting in q.
+func (qs *localQueues) waiting(q *localQueue) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return q.waiters > 0
+}
+
+// pass passes the turn of q from the holder from to the next waiter,
+// with handed, or nil to let the waiter compete for the lock.
+// It returns false if handed was not passed on, because there are no waiters
+// or the turn was taken over from from.
+func (qs *localQueues) pass(name string, q *localQueue, from, handed *Lease) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if q.holder != from {
+		return false
+	}
+	if q.waiters == 0 {
+		q.holder = nil
+		if qs.queues[name] == q {
+			delete(qs.queues, name)
+		}
+		return false
+	}
+	select {
+	case q.turn <- handed:
+		q.holder = handed
+		return true
+	default:
+		// The turn was already passed on by a goroutine that took it over.
+		return false
+	}
+}
+
+// lockLocal is lock for mutexes with a LocalPolicy other than LocalNone.
+// Each Delay spent waiting in the queue uses up one of the tries,
+// so it gives up after about as long as lock does.
+func (m *Mutex) lockLocal(l locker, name, key string) (*Lease, error) {
+	start := time.Now()
+	q, handed, ok := m.queues.enter(name, time.Duration(m.tries-1)*m.delay)
+	if !ok {
+		return nil, ErrFailed
+	}
+	if handed != nil {
+		lease, err := m.takeOver(l, name, key, q, handed)
+		if err != nil {
+			m.queues.pass(name, q, nil, nil)
+			return nil, err
+		}
+		if lease != nil {
+			return lease, nil
+		}
+		m.queues.hold(q, nil)
+	}
+	tries := m.tries
+	if m.delay > 0 {
+		tries -= int(time.Since(start) / m.delay)
+	}
+	if tries < 1 {
+		tries = 1
+	}
+	lease, err := m.acquireLease(l, name, key, q, tries)
+	if err != nil {
+		m.queues.pass(name, q, nil, nil)
+		return nil, err
+	}
+	return lease, nil
+}
+
+var transferScript = redis.NewScript(1, `
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	end
+	return false
+`)
+
+// takeOver moves the lock handed off by the previous holder to a new Lease,
+// with m's own value, metadata and expiry, so the lock identifies its new holder.
+// It returns a nil Lease if the lock could not be moved, after releasing it.
+func (m *Mutex) takeOver(l locker, name, key string, q *localQueue, handed *Lease) (*Lease, error) {
+	if l != locker(m) || !handed.Valid() {
+		handed.releaseAll()
+		return nil, nil
+	}
+	value, err := m.newValue(l)
+	if err != nil {
+		handed.releaseAll()
+		return nil, err
+	}
+	lease := m.newLease(l, name, key, value)
+	lease.queue = q
+
+	start := time.Now()
+	n := 0
+	for _, pool := range m.pools {
+		conn := pool.Get()
+		reply, err := redis.String(transferScript.Do(conn, key, handed.value, value, int(m.expiry/time.Millisecond)))
+		conn.Close()
+		if err == nil && reply == "OK" {
+			n++
+		}
+	}
+	until := m.validUntil(start)
+	if n < m.quorum || !time.Now().Before(until) {
+		handed.releaseAll()
+		lease.releaseAll()
+		return nil, nil
+	}
+	lease.until = until
+	m.queues.hold(q, lease)
+	if !m.registry.add(lease) {
+		lease.releaseAll()
+		m.queues.hold(q, nil)
+		return nil, ErrClosed
+	}
+	return lease, nil
+}
+
+// handoff hands the lock to the next goroutine in the lease's local queue, if the policy allows it,
+// and returns true if it did. The lock is not released; the next goroutine moves it to its own value,
+// so unlocking l again does not release it.
+func (l *Lease) handoff() bool {
+	m := l.mutex
+	if m.local != LocalHandoff || l.locker != locker(m) || !l.Valid() || !m.queues.waiting(l.queue) {
+		return false
+	}
+	l.setHandedOff(true)
+	if !m.queues.pass(l.name, l.queue, l, l) {
+		l.setHandedOff(false)
+		return false
+	}
+	return true
+}
+
+func (l *Lease) setHandedOff(handedOff bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handedOff = handedOff
+}
+
+func (l *Lease) isHandedOff() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.handedOff
+}
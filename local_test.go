@@ -0,0 +1,201 @@
+package redsync_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+// countingConn counts the SET commands sent through it.
+type countingConn struct {
+	redis.Conn
+	sets *int32
+}
+
+func (c countingConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if strings.ToUpper(commandName) == "SET" {
+		atomic.AddInt32(c.sets, 1)
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+var _ = Describe("Local coalescing", func() {
+	// countingPools returns pools that count the SET commands sent to them in sets.
+	countingPools := func(sets *int32) []*redis.Pool {
+		var pools []*redis.Pool
+		for _, pool := range tr.Pools(3) {
+			dial := pool.Dial
+			pools = append(pools, &redis.Pool{MaxIdle: 3, Dial: func() (redis.Conn, error) {
+				conn, err := dial()
+				return countingConn{Conn: conn, sets: sets}, err
+			}})
+		}
+		return pools
+	}
+
+	It("lets one goroutine at a time compete in Redis", func() {
+		var sets int32
+		rs := redsync.New(countingPools(&sets)...)
+		opts := redsync.Blocking()
+		opts.Delay = 10 * time.Millisecond
+		opts.Local = redsync.LocalQueue
+
+		var wg sync.WaitGroup
+		var holders int32
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				lease := mustLock(rs.NewMutex("test-local-queue", opts))
+				Expect(atomic.AddInt32(&holders, 1)).To(Equal(int32(1)))
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&holders, -1)
+				Expect(lease.Unlock()).To(BeTrue())
+			}()
+		}
+		wg.Wait()
+		// Each goroutine acquired the lock on its first try, on each of the 3 nodes.
+		Expect(atomic.LoadInt32(&sets)).To(Equal(int32(8 * 3)))
+	})
+
+	It("hands the lock to a queued goroutine without releasing it", func() {
+		var sets int32
+		pools := countingPools(&sets)
+		rs := redsync.New(pools...)
+		opts := redsync.Blocking()
+		opts.Local = redsync.LocalHandoff
+
+		first := mustLock(rs.NewMutex("test-local-handoff", opts))
+		next := make(chan *redsync.Lease)
+		go func() {
+			defer GinkgoRecover()
+			next <- mustLock(rs.NewMutex("test-local-handoff", opts))
+		}()
+		time.Sleep(50 * time.Millisecond)
+		Expect(first.Unlock()).To(BeTrue())
+
+		var second *redsync.Lease
+		Eventually(next).Should(Receive(&second))
+		Expect(second.Name()).To(Equal("test-local-handoff"))
+		Expect(second.Value()).ToNot(Equal(first.Value()))
+		Expect(second.Valid()).To(BeTrue())
+		Expect(second.IsHeld(context.Background())).To(BeTrue())
+		Expect(atomic.LoadInt32(&sets)).To(Equal(int32(3)))
+
+		// Unlocking the first lease again does not release the lock of the second.
+		Expect(first.Unlock()).To(BeFalse())
+		Expect(second.IsHeld(context.Background())).To(BeTrue())
+		Expect(second.Unlock()).To(BeTrue())
+		Expect(lockErr(redsync.New(pools...).NewMutex("test-local-handoff", redsync.NonBlocking()))).ToNot(HaveOccurred())
+	})
+
+	It("stores the metadata of the goroutine the lock is handed to", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		opts := redsync.Blocking()
+		opts.Local = redsync.LocalHandoff
+		firstOpts, nextOpts := opts, opts
+		firstOpts.Metadata = &redsync.Metadata{RequestID: "first"}
+		nextOpts.Metadata = &redsync.Metadata{RequestID: "next"}
+		nextOpts.Expiry = 2 * opts.Expiry
+
+		first := mustLock(rs.NewMutex("test-local-handoff-owner", firstOpts))
+		next := make(chan *redsync.Lease)
+		go func() {
+			defer GinkgoRecover()
+			next <- mustLock(rs.NewMutex("test-local-handoff-owner", nextOpts))
+		}()
+		time.Sleep(50 * time.Millisecond)
+		Expect(first.Unlock()).To(BeTrue())
+
+		var second *redsync.Lease
+		Eventually(next).Should(Receive(&second))
+		defer second.Unlock()
+		Expect(second.Remaining()).To(BeNumerically(">", opts.Expiry))
+		info, err := rs.Inspect("test-local-handoff-owner")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Value).To(Equal(second.Value()))
+		Expect(info.Metadata.RequestID).To(Equal("next"))
+		Expect(info.TTL).To(BeNumerically(">", opts.Expiry))
+	})
+
+	It("keeps a lock handed off in a session alive", func() {
+		opts := redsync.Blocking()
+		opts.Expiry = 300 * time.Millisecond
+		opts.Local = redsync.LocalHandoff
+		s := redsync.New(tr.Pools(3)...).NewSession(opts)
+		defer s.Close()
+
+		first, err := s.Lock("test-local-handoff-session")
+		Expect(err).ToNot(HaveOccurred())
+		next := make(chan *redsync.Lease)
+		go func() {
+			defer GinkgoRecover()
+			lease, err := s.Lock("test-local-handoff-session")
+			Expect(err).ToNot(HaveOccurred())
+			next <- lease
+		}()
+		time.Sleep(50 * time.Millisecond)
+		Expect(first.Unlock()).To(BeTrue())
+
+		var second *redsync.Lease
+		Eventually(next).Should(Receive(&second))
+		time.Sleep(2 * opts.Expiry)
+		Expect(second.Valid()).To(BeTrue())
+		Expect(second.IsHeld(context.Background())).To(BeTrue())
+	})
+
+	It("takes over the turn of a holder whose lock expired", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		opts := redsync.Blocking()
+		opts.Expiry = 100 * time.Millisecond
+		opts.Delay = 10 * time.Millisecond
+		opts.Local = redsync.LocalQueue
+
+		mustLock(rs.NewMutex("test-local-expired", opts))
+		lease := mustLock(rs.NewMutex("test-local-expired", opts))
+		Expect(lease.Unlock()).To(BeTrue())
+	})
+
+	It("does not wait in the queue with NonBlocking", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		opts := redsync.NonBlocking()
+		opts.Local = redsync.LocalQueue
+		lease := mustLock(rs.NewMutex("test-local-nonblocking", opts))
+		defer lease.Unlock()
+		Expect(lockErr(rs.NewMutex("test-local-nonblocking", opts))).To(Equal(redsync.ErrFailed))
+	})
+
+	It("counts the time waiting in the queue towards the tries", func() {
+		pools := tr.Pools(3)
+		held := mustLock(redsync.New(pools...).NewMutex("test-local-bound", redsync.NonBlocking()))
+		defer held.Unlock()
+
+		rs := redsync.New(pools...)
+		opts := redsync.Blocking()
+		opts.Tries = 5
+		opts.Delay = 50 * time.Millisecond
+		opts.Local = redsync.LocalQueue
+
+		// The first goroutine competes for the lock for 3 delays, while the second waits in the queue.
+		first := opts
+		first.Tries = 4
+		failed := make(chan error)
+		go func() {
+			failed <- lockErr(rs.NewMutex("test-local-bound", first))
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		start := time.Now()
+		Expect(lockErr(rs.NewMutex("test-local-bound", opts))).To(Equal(redsync.ErrFailed))
+		Expect(time.Since(start)).To(BeNumerically("<", 300*time.Millisecond))
+		Expect(<-failed).To(Equal(redsync.ErrFailed))
+	})
+})
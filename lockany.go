@@ -0,0 +1,37 @@
+package redsync
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// LockAny acquires a lock on whichever of names is free first, such as one of a pool of
+// API credentials or test databases, and returns its Lease; its Name identifies the resource.
+// Each round tries every name once, in random order so processes spread over the resources,
+// and rounds are repeated every opts.Delay until a lock is acquired or ctx is done.
+// opts.Tries is not used.
+// Errors other than ErrFailed are returned, rather than retried.
+func (r *Redsync) LockAny(ctx context.Context, names []string, opts MutexOpts) (*Lease, error) {
+	if len(names) == 0 {
+		return nil, errors.New("redsync: no names to lock")
+	}
+	opts.Tries = 1
+	for {
+		for _, i := range rand.Perm(len(names)) {
+			lease, err := r.NewMutex(names[i], opts).Lock()
+			if err == nil {
+				return lease, nil
+			}
+			if err != ErrFailed {
+				return nil, err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(opts.Delay):
+		}
+	}
+}
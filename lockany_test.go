@@ -0,0 +1,49 @@
+package redsync_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("LockAny", func() {
+	names := []string{"test-any-1", "test-any-2", "test-any-3"}
+
+	It("acquires each free name once, then waits for one to be unlocked", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		opts := redsync.NonBlocking()
+		got := make(map[string]*redsync.Lease)
+		for range names {
+			lease, err := rs.LockAny(context.Background(), names, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(names).To(ContainElement(lease.Name()))
+			Expect(got).ToNot(HaveKey(lease.Name()))
+			got[lease.Name()] = lease
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := rs.LockAny(ctx, names, opts)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			got["test-any-2"].Unlock()
+		}()
+		lease, err := rs.LockAny(context.Background(), names, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lease.Name()).To(Equal("test-any-2"))
+
+		Expect(lease.Unlock()).To(BeTrue())
+		Expect(got["test-any-1"].Unlock()).To(BeTrue())
+		Expect(got["test-any-3"].Unlock()).To(BeTrue())
+	})
+
+	It("fails without names", func() {
+		_, err := redsync.New(tr.Pools(1)...).LockAny(context.Background(), nil, redsync.NonBlocking())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,74 @@
+package redsync
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// heartbeatScript records a member in a sorted set, scored by the time its heartbeat expires,
+// removes the members whose heartbeat expired, and returns the remaining ones.
+// Times are taken from the server's clock, so the clocks of members do not matter;
+// replicating the script's effects rather than the script allows reading TIME before writing.
+var heartbeatScript = redis.NewScript(1, `
+	redis.replicate_commands()
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+	local ttl = tonumber(ARGV[1])
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+	redis.call("ZADD", KEYS[1], now + ttl, ARGV[2])
+	redis.call("PEXPIRE", KEYS[1], ttl)
+	return redis.call("ZRANGE", KEYS[1], 0, -1)
+`)
+
+// Heartbeat records member as live in the membership set key for ttl,
+// and returns the live members of the set, sorted.
+// Members are live until ttl after their last heartbeat, by the clock of each node,
+// so call Heartbeat more often than ttl.
+// The set is a sorted set scored by heartbeat deadlines, so each node is read with a single script,
+// unlike List, which scans all keys.
+// Only members live on a quorum of nodes are returned;
+// if fewer than a quorum of nodes reply, the first error is returned.
+func (r *Redsync) Heartbeat(key, member string, ttl time.Duration) ([]string, error) {
+	counts := make(map[string]int)
+	var firstErr error
+	replied := 0
+	for _, pool := range r.pools {
+		conn := pool.Get()
+		members, err := redis.Strings(heartbeatScript.Do(conn, key, int64(ttl/time.Millisecond), member))
+		conn.Close()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		replied++
+		for _, m := range members {
+			counts[m]++
+		}
+	}
+	if replied < Quorum(len(r.pools)) {
+		return nil, firstErr
+	}
+	var live []string
+	for m, n := range counts {
+		if n >= Quorum(len(r.pools)) {
+			live = append(live, m)
+		}
+	}
+	sort.Strings(live)
+	return live, nil
+}
+
+// Leave removes member from the membership set key on all nodes,
+// so other members do not have to wait for its heartbeat to expire.
+// Failures are ignored, since the heartbeat expires anyway.
+func (r *Redsync) Leave(key, member string) {
+	for _, pool := range r.pools {
+		conn := pool.Get()
+		conn.Do("ZREM", key, member)
+		conn.Close()
+	}
+}
@@ -0,0 +1,44 @@
+package redsync_test
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Heartbeat", func() {
+	It("returns the members whose heartbeat did not expire", func() {
+		rs := redsync.New(tr.Pools(3)...)
+		Expect(rs.Heartbeat("test-members", "b", time.Minute)).To(Equal([]string{"b"}))
+		Expect(rs.Heartbeat("test-members", "c", 50*time.Millisecond)).To(Equal([]string{"b", "c"}))
+		Expect(rs.Heartbeat("test-members", "a", time.Minute)).To(Equal([]string{"a", "b", "c"}))
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(rs.Heartbeat("test-members", "a", time.Minute)).To(Equal([]string{"a", "b"}))
+
+		rs.Leave("test-members", "b")
+		Expect(rs.Heartbeat("test-members", "a", time.Minute)).To(Equal([]string{"a"}))
+		rs.Leave("test-members", "a")
+	})
+
+	It("returns only members live on a quorum of nodes", func() {
+		pools := tr.Pools(3)
+		conn := pools[0].Get()
+		_, err := conn.Do("ZADD", "test-members-minority", 1<<52, "stray")
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		rs := redsync.New(pools...)
+		Expect(rs.Heartbeat("test-members-minority", "a", time.Minute)).To(Equal([]string{"a"}))
+		rs.Leave("test-members-minority", "a")
+	})
+
+	It("errors if fewer than a quorum of nodes reply", func() {
+		down := &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")}
+		_, err := redsync.New(append(tr.Pools(1), down, down)...).Heartbeat("test-members-down", "a", time.Minute)
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	})
+})
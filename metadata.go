@@ -0,0 +1,80 @@
+package redsync
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// Metadata describes the owner of a lock.
+// When a Mutex is created with MutexOpts.Metadata, the metadata is stored in the lock key
+// together with the random lock value, so it is set and released atomically with the lock.
+// Use Redsync.Inspect to find out who holds a lock.
+type Metadata struct {
+	// Hostname is the name of the host holding the lock.
+	Hostname string `json:"hostname,omitempty"`
+	// PID is the process ID of the process holding the lock.
+	PID int `json:"pid,omitempty"`
+	// Service is the name of the service holding the lock.
+	Service string `json:"service,omitempty"`
+	// RequestID identifies the request or job the lock was acquired for.
+	RequestID string `json:"request_id,omitempty"`
+	// AcquiredAt is set by Mutex.Lock to the time the lock acquisition started.
+	AcquiredAt time.Time `json:"acquired_at"`
+	// Labels are free-form key/value pairs.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ProcessMetadata returns Metadata for the current process,
+// with Hostname and PID filled in.
+func ProcessMetadata(service string) *Metadata {
+	hostname, _ := os.Hostname()
+	return &Metadata{
+		Hostname: hostname,
+		PID:      os.Getpid(),
+		Service:  service,
+	}
+}
+
+// lockValue is what is stored in the lock key when the lock has metadata.
+// Plain random values are stored as-is, so they remain compatible with older clients.
+type lockValue struct {
+	Token string    `json:"token"`
+	Owner *Metadata `json:"owner"`
+}
+
+// stampOwner returns a copy of owner with AcquiredAt set to acquiredAt, or nil if owner is nil.
+func stampOwner(owner *Metadata, acquiredAt time.Time) *Metadata {
+	if owner == nil {
+		return nil
+	}
+	md := *owner
+	md.AcquiredAt = acquiredAt
+	return &md
+}
+
+// encodeValue returns the string to store in the lock key for the given random token and owner.
+func encodeValue(token string, owner *Metadata) (string, error) {
+	if owner == nil {
+		return token, nil
+	}
+	b, err := json.Marshal(lockValue{Token: token, Owner: owner})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeValue splits a stored lock value into its random token and metadata.
+// Metadata is nil if the value was stored without any.
+func decodeValue(value string) (string, *Metadata) {
+	if !strings.HasPrefix(value, "{") {
+		return value, nil
+	}
+	var lv lockValue
+	if err := json.Unmarshal([]byte(value), &lv); err != nil {
+		return value, nil
+	}
+	return lv.Token, lv.Owner
+}
@@ -0,0 +1,144 @@
+package redsync
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// MultiMutex is a distributed lock on several names at once, such as an account and an order.
+// On each node, either all names are locked or none are, in a single script,
+// so there is no partial acquisition, and no deadlock between processes locking overlapping names.
+// The usual quorum and validity rules apply to the names as a whole.
+// Like a Mutex, a MultiMutex is an immutable definition, and Lock returns a Lease holding the lock.
+// The name of the Lease is the names joined with commas.
+type MultiMutex struct {
+	names []string
+	// m holds the options of the lock; its name is not used.
+	m *Mutex
+}
+
+// NewMultiMutex returns a new distributed lock on all of the given names, with the given options.
+// Names are locked in sorted order, and duplicates are ignored.
+func (r *Redsync) NewMultiMutex(names []string, opts MutexOpts) *MultiMutex {
+	sorted := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	return &MultiMutex{names: sorted, m: r.NewMutex("", opts)}
+}
+
+// String returns a string representation of the mutex.
+func (mm *MultiMutex) String() string {
+	return fmt.Sprintf("redsync.MultiMutex{names: %v, tries: %d, expiry: %s, poolcnt: %d}",
+		mm.names, mm.m.tries, mm.m.expiry.String(), len(mm.m.pools))
+}
+
+// Names returns the names locked by the mutex, sorted.
+func (mm *MultiMutex) Names() []string {
+	return append([]string(nil), mm.names...)
+}
+
+// Lock acquires a lock on all of the mutex's names. See Mutex.Lock.
+// Extending the returned Lease only succeeds on nodes that still hold all of the names.
+func (mm *MultiMutex) Lock() (*Lease, error) {
+	if len(mm.names) == 0 {
+		return nil, errors.New("redsync: no names to lock")
+	}
+	return mm.m.lock(mm, mm.name(), mm.names[0])
+}
+
+// Reclaim takes back a lock on all of the mutex's names held with value. See Mutex.Reclaim.
+func (mm *MultiMutex) Reclaim(value string) (*Lease, error) {
+	if len(mm.names) == 0 {
+		return nil, errors.New("redsync: no names to lock")
+	}
+	return mm.m.reclaim(mm, mm.name(), mm.names[0], value)
+}
+
+// name returns the name of the mutex's leases.
+func (mm *MultiMutex) name() string {
+	return strings.Join(mm.names, ",")
+}
+
+var multiAcquireScript = redis.NewScript(-1, `
+	for _, key in ipairs(KEYS) do
+		if redis.call("EXISTS", key) == 1 then
+			return 0
+		end
+	end
+	for _, key in ipairs(KEYS) do
+		redis.call("SET", key, ARGV[1], "PX", ARGV[2])
+	end
+	return 1
+`)
+
+var multiDeleteScript = redis.NewScript(-1, `
+	local n = 0
+	for _, key in ipairs(KEYS) do
+		if redis.call("GET", key) == ARGV[1] then
+			n = n + redis.call("DEL", key)
+		end
+	end
+	if n == #KEYS then
+		return 1
+	end
+	return 0
+`)
+
+var multiExtendScript = redis.NewScript(-1, `
+	for _, key in ipairs(KEYS) do
+		if redis.call("GET", key) ~= ARGV[1] then
+			return 0
+		end
+	end
+	for _, key in ipairs(KEYS) do
+		redis.call("PEXPIRE", key, ARGV[2])
+	end
+	return 1
+`)
+
+func (mm *MultiMutex) lockKeys() []string {
+	return mm.names
+}
+
+func (mm *MultiMutex) acquire(pool *redis.Pool, value string) (bool, error) {
+	return runScript(pool, multiAcquireScript, mm.names, value, int(mm.m.expiry/time.Millisecond))
+}
+
+func (mm *MultiMutex) release(pool *redis.Pool, value string) bool {
+	ok, _ := runScript(pool, multiDeleteScript, mm.names, value)
+	return ok
+}
+
+func (mm *MultiMutex) extend(pool *redis.Pool, value string) bool {
+	ok, _ := runScript(pool, multiExtendScript, mm.names, value, int(mm.m.expiry/time.Millisecond))
+	return ok
+}
+
+func (mm *MultiMutex) held(conn redis.Conn, value string) (bool, error) {
+	return heldKeys(conn, mm.names, value)
+}
+
+// runScript runs a script taking any number of keys on a single node, and returns true if it returned 1.
+func runScript(pool *redis.Pool, script *redis.Script, keys []string, args ...interface{}) (bool, error) {
+	conn := pool.Get()
+	defer conn.Close()
+	keysAndArgs := make([]interface{}, 0, 1+len(keys)+len(args))
+	keysAndArgs = append(keysAndArgs, len(keys))
+	for _, key := range keys {
+		keysAndArgs = append(keysAndArgs, key)
+	}
+	keysAndArgs = append(keysAndArgs, args...)
+	status, err := redis.Int(script.Do(conn, keysAndArgs...))
+	return err == nil && status == 1, err
+}
@@ -0,0 +1,98 @@
+package redsync_test
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("MultiMutex", func() {
+	// heldOn returns the number of pools on which name holds value.
+	heldOn := func(pools []*redis.Pool, name, value string) int {
+		n := 0
+		for _, pool := range pools {
+			conn := pool.Get()
+			v, _ := redis.String(conn.Do("GET", name))
+			conn.Close()
+			if v == value {
+				n++
+			}
+		}
+		return n
+	}
+
+	It("locks and unlocks all names", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mm := rs.NewMultiMutex([]string{"test-multi-order", "test-multi-account", "test-multi-order"}, redsync.NonBlocking())
+		Expect(mm.Names()).To(Equal([]string{"test-multi-account", "test-multi-order"}))
+
+		lease := mustLock(mm)
+		Expect(lease.Name()).To(Equal("test-multi-account,test-multi-order"))
+		Expect(heldOn(pools, "test-multi-account", lease.Value())).To(Equal(3))
+		Expect(heldOn(pools, "test-multi-order", lease.Value())).To(Equal(3))
+		Expect(lockErr(rs.NewMutex("test-multi-order", redsync.NonBlocking()))).To(Equal(redsync.ErrFailed))
+		Expect(lease.Extend()).To(BeTrue())
+
+		Expect(lease.Unlock()).To(BeTrue())
+		Expect(heldOn(pools, "test-multi-account", "")).To(Equal(3))
+		Expect(heldOn(pools, "test-multi-order", "")).To(Equal(3))
+		Expect(lease.Unlock()).To(BeFalse())
+	})
+
+	It("locks no names on a node where any is held", func() {
+		pools := tr.Pools(3)
+		for _, pool := range pools[:2] {
+			conn := pool.Get()
+			_, err := conn.Do("SET", "test-multi-partial-b", "foobar", "PX", 10000)
+			conn.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		mm := redsync.New(pools...).NewMultiMutex([]string{"test-multi-partial-a", "test-multi-partial-b"}, redsync.NonBlocking())
+		Expect(lockErr(mm)).To(Equal(redsync.ErrFailed))
+		Expect(heldOn(pools, "test-multi-partial-a", "")).To(Equal(3))
+		Expect(heldOn(pools, "test-multi-partial-b", "foobar")).To(Equal(2))
+	})
+
+	It("acquires with a quorum of nodes", func() {
+		pools := tr.Pools(3)
+		conn := pools[0].Get()
+		_, err := conn.Do("SET", "test-multi-quorum-b", "foobar", "PX", 10000)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		mm := redsync.New(pools...).NewMultiMutex([]string{"test-multi-quorum-a", "test-multi-quorum-b"}, redsync.NonBlocking())
+		lease := mustLock(mm)
+		Expect(heldOn(pools, "test-multi-quorum-a", lease.Value())).To(Equal(2))
+		Expect(lease.Unlock()).To(BeTrue())
+		Expect(heldOn(pools, "test-multi-quorum-b", "foobar")).To(Equal(1))
+	})
+
+	It("does not deadlock with overlapping names in different orders", func() {
+		pools := tr.Pools(3)
+		var wg sync.WaitGroup
+		var counter int32
+		for i := 0; i < 8; i++ {
+			names := []string{"test-multi-deadlock-a", "test-multi-deadlock-b"}
+			if i%2 == 1 {
+				names[0], names[1] = names[1], names[0]
+			}
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				mm := redsync.New(pools...).NewMultiMutex(names, redsync.Blocking())
+				lease := mustLock(mm)
+				atomic.AddInt32(&counter, 1)
+				Expect(lease.Unlock()).To(BeTrue())
+			}()
+		}
+		wg.Wait()
+		Expect(atomic.LoadInt32(&counter)).To(Equal(int32(8)))
+	})
+})
@@ -1,18 +1,16 @@
 package redsync
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"time"
 
 	"fmt"
 	"github.com/gomodule/redigo/redis"
 )
 
-// Mutex is a distributed mutual exclusion lock.
-// Note that a redsync.Mutex is not goroutine-safe.
-// Each goroutine should create its own Mutex instance for locking.
-// Note that Redsync instances are threadsafe, so they can be reused across goroutines.
+// Mutex is the definition of a distributed mutual exclusion lock: its name, the nodes it is held on,
+// and the options for acquiring it.
+// A Mutex is immutable, so it is goroutine-safe, and can be kept in a package-level variable
+// like a sync.Mutex. Each call to Lock returns a new Lease, which holds the lock.
 type Mutex struct {
 	name   string
 	expiry time.Duration
@@ -24,10 +22,16 @@ type Mutex struct {
 
 	quorum int
 
-	value string
-	until time.Time
+	owner *Metadata
+
+	generate ValueGenerator
 
 	pools []*redis.Pool
+	// registry tracks the leases of the Redsync the mutex was created with.
+	registry *registry
+
+	local  LocalPolicy
+	queues *localQueues
 }
 
 // String returns a string representation of the mutex.
@@ -41,53 +45,94 @@ func (m *Mutex) Name() string {
 	return m.name
 }
 
-// Value returns the mutex value.
-func (m *Mutex) Value() string {
-	return m.value
-}
-
 // Lock acquires a lock on the mutex with the receiver's Name.
-// If Lock returns nil, the lock is acquired. Callers should make sure Unlock is called,
-// usually via defer m.Unlock().
-// If Lock returns ErrFailed, the lock could not be acquired because it was held by another mutex.
+// If Lock returns a nil error, the lock is acquired and held by the returned Lease.
+// Callers should make sure the Lease is unlocked, usually via defer lease.Unlock().
+// If Lock returns ErrFailed, the lock could not be acquired because it was held by another lease.
 // Callers may wish to call Lock() again to retry.
 // If Lock returns any other error, the lock may not be acquire-able do to an unexpected error,
 // like if redis is not running.
-func (m *Mutex) Lock() error {
-	value, err := m.genValue()
+func (m *Mutex) Lock() (*Lease, error) {
+	return m.lock(m, m.name, m.name)
+}
+
+// lock runs the acquisition loop of Lock, using l to acquire and release the lock on each node,
+// and returns a Lease with the given name and lock key.
+// It is shared with the other kinds of locks that are built on a Mutex's options.
+func (m *Mutex) lock(l locker, name, key string) (*Lease, error) {
+	if m.local != LocalNone {
+		return m.lockLocal(l, name, key)
+	}
+	return m.acquireLease(l, name, key, nil, m.tries)
+}
+
+// acquireLease tries to acquire the lock with a new value, and returns its Lease.
+// It tries up to tries times. If q is not nil, the lease becomes the holder of the turn of the local queue q.
+func (m *Mutex) acquireLease(l locker, name, key string, q *localQueue, tries int) (*Lease, error) {
+	value, err := m.newValue(l)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	lease := m.newLease(l, name, key, value)
+	lease.queue = q
 
-	for i := 0; i < m.tries; i++ {
+	for i := 0; i < tries; i++ {
 		if i != 0 {
 			time.Sleep(m.delay)
 		}
 
 		start := time.Now()
 
-		acquired, err := m.acquireAll(value)
+		acquired, err := lease.acquireAll()
 		if err != nil {
-			m.releaseAll(value)
-			return err
+			lease.releaseAll()
+			return nil, err
 		}
 
-		until := time.Now().Add(m.expiry - time.Now().Sub(start) - time.Duration(int64(float64(m.expiry)*m.factor)) + 2*time.Millisecond)
+		until := m.validUntil(start)
 		if acquired >= m.quorum && time.Now().Before(until) {
-			m.value = value
-			m.until = until
-			return nil
+			lease.until = until
+			if q != nil {
+				m.queues.hold(q, lease)
+			}
+			if !m.registry.add(lease) {
+				lease.releaseAll()
+				if q != nil {
+					m.queues.hold(q, nil)
+				}
+				return nil, ErrClosed
+			}
+			return lease, nil
 		}
-		m.releaseAll(value)
+		lease.releaseAll()
 	}
 
-	return ErrFailed
+	return nil, ErrFailed
+}
+
+// Reclaim takes back a lock held with value, as returned from Lease.Value,
+// such as by a process that persisted the value of its lock and restarted,
+// without waiting for the lock to expire.
+// The lock is extended on a quorum of nodes before the Lease is returned;
+// if it cannot be, because the lock has expired or is held with another value, Reclaim returns ErrNotHeld.
+func (m *Mutex) Reclaim(value string) (*Lease, error) {
+	return m.reclaim(m, m.name, m.name, value)
 }
 
-// Unlock unlocks m and returns the status of unlock.
-func (m *Mutex) Unlock() bool {
-	released := m.releaseAll(m.value)
-	return released >= m.quorum
+// reclaim returns a Lease holding the lock with value, like lock.
+func (m *Mutex) reclaim(l locker, name, key, value string) (*Lease, error) {
+	lease := m.newLease(l, name, key, value)
+	if !lease.Extend() {
+		return nil, ErrNotHeld
+	}
+	if !m.registry.add(lease) {
+		return nil, ErrClosed
+	}
+	return lease, nil
+}
+
+func (m *Mutex) newLease(l locker, name, key, value string) *Lease {
+	return &Lease{mutex: m, locker: l, name: name, key: key, value: value, done: make(chan struct{})}
 }
 
 // WithLock invokes f if the lock was successfully invoked. See Lock for more info.
@@ -96,39 +141,48 @@ func (m *Mutex) Unlock() bool {
 // The error is only non-nil if an unexpected error occurred.
 // In other words, if Lock() returns ErrFailed, WithLock returns an error of nil.
 func (m *Mutex) WithLock(f func()) (bool, error) {
-	err := m.Lock()
+	lease, err := m.Lock()
 	if err == ErrFailed {
 		return false, nil
 	}
 	if err != nil {
 		return false, err
 	}
-	defer m.Unlock()
+	defer lease.Unlock()
 	f()
 	return true, nil
 }
 
-func (m *Mutex) genValue() (string, error) {
-	b := make([]byte, 32)
-	_, err := rand.Read(b)
+// validUntil returns the time until which a lock acquired or extended at start is valid,
+// accounting for clock drift.
+func (m *Mutex) validUntil(start time.Time) time.Time {
+	return time.Now().Add(m.expiry - time.Now().Sub(start) - time.Duration(int64(float64(m.expiry)*m.factor)) + 2*time.Millisecond)
+}
+
+// newValue returns a new value for the lock keys of l,
+// made of a token from the mutex's ValueGenerator and its owner metadata, if any.
+func (m *Mutex) newValue(l locker) (string, error) {
+	owner := stampOwner(m.owner, time.Now())
+	token, err := m.generate(l.lockKeys(), owner)
 	if err != nil {
 		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(b), nil
+	return encodeValue(token, owner)
 }
 
-func (m *Mutex) acquireAll(value string) (int, error) {
-	n := 0
-	for _, pool := range m.pools {
-		ok, err := m.acquire(pool, value)
-		if ok {
-			n++
-		}
-		if err != nil {
-			return n, err
-		}
-	}
-	return n, nil
+// locker acquires, releases and extends a kind of lock on a single node.
+// Mutex, MultiMutex and PathMutex implement it, and share Lease to hold their locks.
+type locker interface {
+	acquire(pool *redis.Pool, value string) (bool, error)
+	release(pool *redis.Pool, value string) bool
+	extend(pool *redis.Pool, value string) bool
+	held(conn redis.Conn, value string) (bool, error)
+	// lockKeys returns the keys the value is stored in, which are passed to the ValueGenerator.
+	lockKeys() []string
+}
+
+func (m *Mutex) lockKeys() []string {
+	return []string{m.name}
 }
 
 func (m *Mutex) acquire(pool *redis.Pool, value string) (bool, error) {
@@ -145,16 +199,6 @@ func (m *Mutex) acquire(pool *redis.Pool, value string) (bool, error) {
 	return false, err
 }
 
-func (m *Mutex) releaseAll(value string) int {
-	n := 0
-	for _, pool := range m.pools {
-		if m.release(pool, value) {
-			n++
-		}
-	}
-	return n
-}
-
 var deleteScript = redis.NewScript(1, `
 	if redis.call("GET", KEYS[1]) == ARGV[1] then
 		return redis.call("DEL", KEYS[1])
@@ -166,6 +210,43 @@ var deleteScript = redis.NewScript(1, `
 func (m *Mutex) release(pool *redis.Pool, value string) bool {
 	conn := pool.Get()
 	defer conn.Close()
-	status, err := deleteScript.Do(conn, m.name, value)
+	status, err := redis.Int(deleteScript.Do(conn, m.name, value))
+	return err == nil && status != 0
+}
+
+var extendScript = redis.NewScript(1, `
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	else
+		return 0
+	end
+`)
+
+func (m *Mutex) extend(pool *redis.Pool, value string) bool {
+	conn := pool.Get()
+	defer conn.Close()
+	status, err := redis.Int(extendScript.Do(conn, m.name, value, int(m.expiry/time.Millisecond)))
 	return err == nil && status != 0
 }
+
+func (m *Mutex) held(conn redis.Conn, value string) (bool, error) {
+	return heldKeys(conn, []string{m.name}, value)
+}
+
+// heldKeys returns true if all of keys hold value on the node of conn.
+func heldKeys(conn redis.Conn, keys []string, value string) (bool, error) {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	values, err := redis.Strings(conn.Do("MGET", args...))
+	if err != nil {
+		return false, err
+	}
+	for _, v := range values {
+		if v != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
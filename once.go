@@ -0,0 +1,90 @@
+package redsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInProgress is returned from Once.Do when the function is being run by another process,
+// and Once.Wait is false.
+var ErrInProgress = errors.New("redsync: already in progress in another process")
+
+// OnceError is returned from Once.Do when it waited for another process to run the function,
+// and the function failed there.
+type OnceError struct {
+	// Name is the name of the Once.
+	Name string
+	// Message is the error message of the failed function.
+	Message string
+}
+
+func (e *OnceError) Error() string {
+	return fmt.Sprintf("redsync: %s failed in another process: %s", e.Name, e.Message)
+}
+
+// Once runs a function successfully at most once across all processes, within a TTL.
+// It is like sync.Once, but distributed, and useful for things like schema migrations
+// that are started by every replica of a service at deploy time.
+// Use Redsync.Once to create one.
+//
+// The function is run while holding a lock with the name of the Once,
+// which is extended for as long as the function runs.
+// When it succeeds, completion is recorded on a quorum of nodes for the TTL,
+// and later calls to Do return nil without running the function.
+// A failed function is not recorded as complete, so it will run again on the next call to Do.
+type Once struct {
+	rs   *Redsync
+	name string
+	ttl  time.Duration
+
+	// Wait controls what Do does while the function is running in another process.
+	// If true (the default), Do waits for it to finish, and returns its result.
+	// If false, Do returns ErrInProgress.
+	Wait bool
+	// PollInterval is how often Do checks whether the function finished in another process.
+	// Defaults to 100ms.
+	PollInterval time.Duration
+	// Expiry is the expiry of the lock held while running the function.
+	// The lock is extended while the function runs, so this only matters if the process dies.
+	// Defaults to 8s.
+	Expiry time.Duration
+}
+
+// Once returns a Once with the given name, that records completion for ttl.
+func (r *Redsync) Once(name string, ttl time.Duration) *Once {
+	return &Once{
+		rs:           r,
+		name:         name,
+		ttl:          ttl,
+		Wait:         true,
+		PollInterval: 100 * time.Millisecond,
+		Expiry:       8 * time.Second,
+	}
+}
+
+// Do runs f, unless it already ran successfully in any process within the TTL.
+// If f is running in another process, Do waits for it or returns ErrInProgress, depending on Wait.
+// If Do waited, it returns nil if f succeeded, and a *OnceError if it failed.
+// If f ran in this process, Do returns its error.
+// If f succeeded but its completion could not be recorded because the lock was lost,
+// Do returns ErrNotHeld.
+func (o *Once) Do(ctx context.Context, f func() error) error {
+	fl := &flight{
+		rs:           o.rs,
+		name:         o.name,
+		recordKey:    o.name + ":done",
+		ttl:          o.ttl,
+		wait:         o.Wait,
+		pollInterval: o.PollInterval,
+		expiry:       o.Expiry,
+		remoteErr: func(msg string) error {
+			return &OnceError{Name: o.name, Message: msg}
+		},
+	}
+	_, err := fl.do(ctx, func() ([]byte, error) {
+		return nil, f()
+	})
+	return err
+}
@@ -0,0 +1,100 @@
+package redsync_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Once", func() {
+	newOnce := func(name string) *redsync.Once {
+		once := redsync.New(tr.Pools(3)...).Once(name, time.Minute)
+		once.PollInterval = 10 * time.Millisecond
+		once.Expiry = 100 * time.Millisecond
+		return once
+	}
+
+	doAll := func(name string, n int, f func() error) []error {
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				errs[i] = newOnce(name).Do(context.Background(), f)
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	It("runs a function once across processes", func() {
+		var calls int32
+		f := func() error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(250 * time.Millisecond)
+			return nil
+		}
+		for _, err := range doAll("test-once", 5, f) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(newOnce("test-once").Do(context.Background(), f)).To(Succeed())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	It("reports failures to waiters, and runs again after a failure", func() {
+		var calls int32
+		failing := func() error {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(100 * time.Millisecond)
+			return errors.New("migration failed")
+		}
+		errs := doAll("test-once-fail", 3, failing)
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		var onceErrs int
+		for _, err := range errs {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("migration failed"))
+			if _, ok := err.(*redsync.OnceError); ok {
+				onceErrs++
+			}
+		}
+		Expect(onceErrs).To(Equal(2))
+
+		Expect(newOnce("test-once-fail").Do(context.Background(), func() error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})).To(Succeed())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("does not wait if Wait is false", func() {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			Expect(newOnce("test-once-nowait").Do(context.Background(), func() error {
+				close(started)
+				<-release
+				return nil
+			})).To(Succeed())
+		}()
+		<-started
+
+		once := newOnce("test-once-nowait")
+		once.Wait = false
+		Expect(once.Do(context.Background(), func() error { return nil })).To(Equal(redsync.ErrInProgress))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(newOnce("test-once-nowait").Do(ctx, func() error { return nil })).To(Equal(context.DeadlineExceeded))
+		close(release)
+	})
+})
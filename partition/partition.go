@@ -0,0 +1,234 @@
+// Package partition divides a set of named shards among cooperating workers,
+// using a Redsync lock per shard.
+//
+// Each worker registers itself in a membership set with a heartbeat, and claims its fair share of the shards:
+// with N live workers and M shards, every worker owns M/N shards, rounded up for some of them.
+// As workers join and leave, workers owning too many shards release them,
+// and workers owning too few claim the released or expired ones.
+// The heartbeat is sent and all locks are extended every third of Opts.Expiry for as long as the worker runs,
+// so the shards of a worker that dies are reassigned after at most Expiry.
+//
+//	shards := make([]string, 256)
+//	for i := range shards {
+//		shards[i] = strconv.Itoa(i)
+//	}
+//	w := partition.New(rs, "queue-consumers", shards, partition.Opts{
+//		OnAssigned: startConsuming,
+//		OnRevoked:  stopConsuming,
+//	})
+//	w.Run(ctx)
+package partition
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rgalanakis/redsync"
+)
+
+// Opts are the options for a Worker.
+type Opts struct {
+	// Prefix is prepended to the group name to get the names of the membership set and shard locks.
+	// Defaults to "partition:".
+	Prefix string
+	// Expiry is the expiry of membership heartbeats and shard locks. Heartbeats are sent and locks are extended
+	// every Expiry/3, which is also how often the worker rebalances. Defaults to 8s.
+	Expiry time.Duration
+	// Identity is stored in the shard locks of the worker, to identify it.
+	// Defaults to redsync.ProcessMetadata with the group name as service.
+	Identity *redsync.Metadata
+	// OnAssigned is called when the worker starts owning a shard. It is optional.
+	OnAssigned func(shard string)
+	// OnRevoked is called when the worker stops owning a shard. It is optional.
+	// When the worker releases a shard to rebalance, OnRevoked is called before the shard's lock is released,
+	// so work on the shard can be stopped before another worker claims it.
+	// When a shard's lock is lost, OnRevoked is called as soon as that is noticed.
+	OnRevoked func(shard string)
+}
+
+// Worker claims a fair share of the shards of a group, coordinating with the other workers of the group.
+// Its methods are safe to use from multiple goroutines.
+type Worker struct {
+	rs     *redsync.Redsync
+	group  string
+	shards []string
+	opts   Opts
+	id     string
+
+	mu    sync.Mutex
+	owned map[string]*redsync.Lease
+}
+
+// New returns a Worker for the named group, dividing the given shards.
+// All workers of a group must use the same shards.
+func New(rs *redsync.Redsync, group string, shards []string, opts Opts) *Worker {
+	if opts.Prefix == "" {
+		opts.Prefix = "partition:"
+	}
+	if opts.Expiry == 0 {
+		opts.Expiry = 8 * time.Second
+	}
+	if opts.Identity == nil {
+		opts.Identity = redsync.ProcessMetadata(group)
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return &Worker{
+		rs:     rs,
+		group:  group,
+		shards: shards,
+		opts:   opts,
+		id:     hex.EncodeToString(b),
+		owned:  make(map[string]*redsync.Lease),
+	}
+}
+
+// Owned returns the names of the shards the worker owns, sorted.
+func (w *Worker) Owned() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	owned := make([]string, 0, len(w.owned))
+	for shard := range w.owned {
+		owned = append(owned, shard)
+	}
+	sort.Strings(owned)
+	return owned
+}
+
+// Run joins the group and claims shards until ctx is done,
+// then releases all shards and leaves the group.
+// A Worker must only be run once at a time.
+// Errors talking to Redis are retried until ctx is done, so Run always returns ctx.Err().
+func (w *Worker) Run(ctx context.Context) error {
+	defer func() {
+		for _, shard := range w.Owned() {
+			w.release(shard)
+		}
+		w.rs.Leave(w.membersKey(), w.id)
+	}()
+	ticker := time.NewTicker(w.opts.Expiry / 3)
+	defer ticker.Stop()
+	for {
+		live, err := w.rs.Heartbeat(w.membersKey(), w.id, w.opts.Expiry)
+		w.extend()
+		if err == nil {
+			w.rebalance(ctx, live)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// extend extends the locks of all owned shards, revoking the ones that were lost.
+func (w *Worker) extend() {
+	for _, shard := range w.Owned() {
+		w.mu.Lock()
+		lease := w.owned[shard]
+		w.mu.Unlock()
+		if !lease.Extend() {
+			w.mu.Lock()
+			delete(w.owned, shard)
+			w.mu.Unlock()
+			w.revoked(shard)
+		}
+	}
+}
+
+// rebalance claims or releases shards so the worker owns its fair share among the live members,
+// which are sorted.
+func (w *Worker) rebalance(ctx context.Context, live []string) {
+	rank := sort.SearchStrings(live, w.id)
+	if rank == len(live) || live[rank] != w.id {
+		return
+	}
+	target := len(w.shards) / len(live)
+	if rank < len(w.shards)%len(live) {
+		target++
+	}
+
+	// Prefer shards starting at the worker's position in the group,
+	// so workers mostly try different shards.
+	start := rank * len(w.shards) / len(live)
+	preferred := make([]string, 0, len(w.shards))
+	for i := range w.shards {
+		preferred = append(preferred, w.shards[(start+i)%len(w.shards)])
+	}
+
+	owned := len(w.Owned())
+	if owned > target {
+		// Release the least preferred shards first.
+		for i := len(preferred) - 1; i >= 0 && owned > target; i-- {
+			if w.owns(preferred[i]) {
+				w.release(preferred[i])
+				owned--
+			}
+		}
+		return
+	}
+	for _, shard := range preferred {
+		if owned >= target || ctx.Err() != nil {
+			return
+		}
+		if w.owns(shard) {
+			continue
+		}
+		lease, err := w.rs.NewMutex(w.shardName(shard), w.mutexOpts()).Lock()
+		if err != nil {
+			continue
+		}
+		w.mu.Lock()
+		w.owned[shard] = lease
+		w.mu.Unlock()
+		owned++
+		if w.opts.OnAssigned != nil {
+			w.opts.OnAssigned(shard)
+		}
+	}
+}
+
+func (w *Worker) owns(shard string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.owned[shard] != nil
+}
+
+// release revokes an owned shard and unlocks it.
+func (w *Worker) release(shard string) {
+	w.mu.Lock()
+	lease := w.owned[shard]
+	delete(w.owned, shard)
+	w.mu.Unlock()
+	w.revoked(shard)
+	lease.Unlock()
+}
+
+func (w *Worker) revoked(shard string) {
+	if w.opts.OnRevoked != nil {
+		w.opts.OnRevoked(shard)
+	}
+}
+
+func (w *Worker) mutexOpts() redsync.MutexOpts {
+	opts := redsync.NonBlocking()
+	opts.Expiry = w.opts.Expiry
+	opts.Metadata = w.opts.Identity
+	return opts
+}
+
+func (w *Worker) membersKey() string {
+	return fmt.Sprintf("%s%s:members", w.opts.Prefix, w.group)
+}
+
+func (w *Worker) shardName(shard string) string {
+	return fmt.Sprintf("%s%s:shards:%s", w.opts.Prefix, w.group, shard)
+}
@@ -0,0 +1,179 @@
+package partition_test
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+	"github.com/rgalanakis/redsync/partition"
+	"github.com/rgalanakis/redsync/rstest"
+)
+
+func TestPartition(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Partition Suite")
+}
+
+var tr = make(rstest.TempServers, 3)
+
+var _ = BeforeSuite(func() {
+	tr.Start()
+})
+
+var _ = AfterSuite(func() {
+	tr.Stop()
+})
+
+// worker is a running partition.Worker that records its callbacks.
+type worker struct {
+	*partition.Worker
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	mu       sync.Mutex
+	assigned map[string]bool
+	revoked  int
+}
+
+func startWorker(group string, shards []string) *worker {
+	w := &worker{stopped: make(chan struct{}), assigned: make(map[string]bool)}
+	w.Worker = partition.New(redsync.New(tr.Pools(3)...), group, shards, partition.Opts{
+		Expiry: 150 * time.Millisecond,
+		OnAssigned: func(shard string) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			Expect(w.assigned).ToNot(HaveKey(shard))
+			w.assigned[shard] = true
+		},
+		OnRevoked: func(shard string) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			Expect(w.assigned).To(HaveKey(shard))
+			delete(w.assigned, shard)
+			w.revoked++
+		},
+	})
+	var ctx context.Context
+	ctx, w.cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		defer close(w.stopped)
+		Expect(w.Run(ctx)).To(Equal(context.Canceled))
+	}()
+	return w
+}
+
+func (w *worker) stop() {
+	w.cancel()
+	<-w.stopped
+}
+
+// callbackOwned returns the shards owned according to the callbacks.
+func (w *worker) callbackOwned() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var owned []string
+	for shard := range w.assigned {
+		owned = append(owned, shard)
+	}
+	sort.Strings(owned)
+	return owned
+}
+
+// sizes returns the number of shards owned by each worker, sorted,
+// once every shard is owned by exactly one worker and the callbacks agree.
+func sizes(workers ...*worker) []int {
+	owners := make(map[string]int)
+	var sizes []int
+	for _, w := range workers {
+		owned := w.Owned()
+		if len(owned) != len(w.callbackOwned()) {
+			return nil
+		}
+		for _, shard := range owned {
+			owners[shard]++
+		}
+		sizes = append(sizes, len(owned))
+	}
+	for _, n := range owners {
+		if n != 1 {
+			return nil
+		}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+var _ = Describe("Worker", func() {
+	shards := make([]string, 10)
+	for i := range shards {
+		shards[i] = strconv.Itoa(i)
+	}
+
+	It("balances shards as workers join and leave", func() {
+		w1 := startWorker("partition-balance", shards)
+		Eventually(func() []int { return sizes(w1) }, 5*time.Second).Should(Equal([]int{10}))
+
+		w2 := startWorker("partition-balance", shards)
+		w3 := startWorker("partition-balance", shards)
+		Eventually(func() []int { return sizes(w1, w2, w3) }, 5*time.Second).Should(Equal([]int{3, 3, 4}))
+
+		w1.stop()
+		Expect(w1.Owned()).To(BeEmpty())
+		Expect(w1.callbackOwned()).To(BeEmpty())
+		Eventually(func() []int { return sizes(w2, w3) }, 5*time.Second).Should(Equal([]int{5, 5}))
+
+		w2.stop()
+		w3.stop()
+	})
+
+	It("revokes shards whose locks are lost", func() {
+		w1 := startWorker("partition-lost", shards)
+		w2 := startWorker("partition-lost", shards)
+		Eventually(func() []int { return sizes(w1, w2) }, 5*time.Second).Should(Equal([]int{5, 5}))
+
+		lost := w1.Owned()
+		for _, pool := range tr.Pools(3) {
+			conn := pool.Get()
+			for _, shard := range lost {
+				_, err := conn.Do("DEL", "partition:partition-lost:shards:"+shard)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			conn.Close()
+		}
+		Eventually(func() int {
+			w1.mu.Lock()
+			defer w1.mu.Unlock()
+			return w1.revoked
+		}, 5*time.Second).Should(BeNumerically(">=", len(lost)))
+		Eventually(func() []int { return sizes(w1, w2) }, 5*time.Second).Should(Equal([]int{5, 5}))
+
+		w1.stop()
+		w2.stop()
+	})
+
+	It("keeps the members of the group in a sorted set", func() {
+		members := func() int {
+			conn := tr.Pools(1)[0].Get()
+			defer conn.Close()
+			n, err := redis.Int(conn.Do("ZCARD", "partition:partition-members:members"))
+			Expect(err).ToNot(HaveOccurred())
+			return n
+		}
+		w1 := startWorker("partition-members", shards)
+		w2 := startWorker("partition-members", shards)
+		Eventually(members, 5*time.Second).Should(Equal(2))
+
+		w1.stop()
+		Expect(members()).To(Equal(1))
+		w2.stop()
+		Expect(members()).To(Equal(0))
+	})
+})
@@ -0,0 +1,159 @@
+package redsync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// PathMutex is a distributed lock on a path like "tenant/42/project/7",
+// that excludes locks on both the ancestors and the descendants of its path.
+// For example, a migration can lock "tenant/42" as a whole, while requests lock individual projects.
+// Use Redsync.NewPathMutex to create one.
+//
+// The lock on a path is held in a key named after the path, like a Mutex.
+// While it is held, an intention marker is kept on each ancestor,
+// so locking the ancestor can see that a descendant is held.
+// Checking for conflicts, locking and marking the ancestors happen in a single script on each node.
+// Intention markers expire by the clock of the process that set them,
+// so the clocks of processes locking paths must roughly agree.
+// Like a Mutex, a PathMutex is an immutable definition, and Lock returns a Lease holding the lock.
+type PathMutex struct {
+	path string
+	// keys are the path's lock key, its intention key,
+	// and the lock and intention keys of its ancestors, from the root down.
+	keys []string
+	// m holds the options of the lock.
+	m *Mutex
+}
+
+// NewPathMutex returns a new distributed lock on path, with the given options.
+// Path components are separated by "/"; empty components are ignored.
+func (r *Redsync) NewPathMutex(path string, opts MutexOpts) *PathMutex {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	path = strings.Join(parts, "/")
+	ancestors := make([]string, 0, len(parts))
+	for i := 1; i < len(parts); i++ {
+		ancestors = append(ancestors, strings.Join(parts[:i], "/"))
+	}
+	keys := []string{path, intentKey(path)}
+	keys = append(keys, ancestors...)
+	for _, ancestor := range ancestors {
+		keys = append(keys, intentKey(ancestor))
+	}
+	return &PathMutex{path: path, keys: keys, m: r.NewMutex(path, opts)}
+}
+
+// intentKey returns the key holding the intention markers of the descendants of path.
+func intentKey(path string) string {
+	return "redsync:intent:" + path
+}
+
+// String returns a string representation of the mutex.
+func (pm *PathMutex) String() string {
+	return fmt.Sprintf("redsync.PathMutex{path: %s, tries: %d, expiry: %s, poolcnt: %d}",
+		pm.path, pm.m.tries, pm.m.expiry.String(), len(pm.m.pools))
+}
+
+// Path returns the path of the mutex, which is also the name of its lock.
+func (pm *PathMutex) Path() string {
+	return pm.path
+}
+
+// Lock acquires a lock on the mutex's path. See Mutex.Lock.
+// It fails if the path, any of its ancestors, or any of its descendants is locked.
+// Unlocking the returned Lease also removes its intention markers, and extending it extends them.
+func (pm *PathMutex) Lock() (*Lease, error) {
+	return pm.m.lock(pm, pm.path, pm.path)
+}
+
+// Reclaim takes back a lock on the mutex's path held with value. See Mutex.Reclaim.
+func (pm *PathMutex) Reclaim(value string) (*Lease, error) {
+	return pm.m.reclaim(pm, pm.path, pm.path, value)
+}
+
+// The keys of the path scripts are as described for PathMutex.keys.
+// The number of ancestors is derived from the number of keys.
+
+var pathAcquireScript = redis.NewScript(-1, `
+	local k = (#KEYS - 2) / 2
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		return 0
+	end
+	for i = 1, k do
+		if redis.call("EXISTS", KEYS[2 + i]) == 1 then
+			return 0
+		end
+	end
+	redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", ARGV[3])
+	if redis.call("ZCARD", KEYS[2]) > 0 then
+		return 0
+	end
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	local deadline = tonumber(ARGV[3]) + tonumber(ARGV[2])
+	for i = 1, k do
+		local intent = KEYS[2 + k + i]
+		redis.call("ZADD", intent, deadline, ARGV[1])
+		if redis.call("PTTL", intent) < tonumber(ARGV[2]) then
+			redis.call("PEXPIRE", intent, ARGV[2])
+		end
+	end
+	return 1
+`)
+
+var pathDeleteScript = redis.NewScript(-1, `
+	local k = (#KEYS - 2) / 2
+	for i = 1, k do
+		redis.call("ZREM", KEYS[2 + k + i], ARGV[1])
+	end
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+var pathExtendScript = redis.NewScript(-1, `
+	local k = (#KEYS - 2) / 2
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return 0
+	end
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	local deadline = tonumber(ARGV[3]) + tonumber(ARGV[2])
+	for i = 1, k do
+		local intent = KEYS[2 + k + i]
+		redis.call("ZADD", intent, deadline, ARGV[1])
+		if redis.call("PTTL", intent) < tonumber(ARGV[2]) then
+			redis.call("PEXPIRE", intent, ARGV[2])
+		end
+	end
+	return 1
+`)
+
+func (pm *PathMutex) lockKeys() []string {
+	return []string{pm.path}
+}
+
+func (pm *PathMutex) acquire(pool *redis.Pool, value string) (bool, error) {
+	return runScript(pool, pathAcquireScript, pm.keys, value, int(pm.m.expiry/time.Millisecond), nowMillis())
+}
+
+func (pm *PathMutex) release(pool *redis.Pool, value string) bool {
+	ok, _ := runScript(pool, pathDeleteScript, pm.keys, value)
+	return ok
+}
+
+func (pm *PathMutex) extend(pool *redis.Pool, value string) bool {
+	ok, _ := runScript(pool, pathExtendScript, pm.keys, value, int(pm.m.expiry/time.Millisecond), nowMillis())
+	return ok
+}
+
+func (pm *PathMutex) held(conn redis.Conn, value string) (bool, error) {
+	return heldKeys(conn, pm.keys[:1], value)
+}
@@ -0,0 +1,63 @@
+package redsync_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("PathMutex", func() {
+	var rs *redsync.Redsync
+
+	BeforeEach(func() {
+		rs = redsync.New(tr.Pools(3)...)
+	})
+
+	lock := func(path string) error {
+		return lockErr(rs.NewPathMutex(path, redsync.NonBlocking()))
+	}
+
+	It("normalizes paths", func() {
+		Expect(rs.NewPathMutex("/tenant//42/", redsync.NonBlocking()).Path()).To(Equal("tenant/42"))
+	})
+
+	It("excludes descendants while a path is held", func() {
+		tenant := rs.NewPathMutex("test-path-1/tenant/42", redsync.NonBlocking())
+		tenantLease := mustLock(tenant)
+		Expect(lock("test-path-1/tenant/42")).To(Equal(redsync.ErrFailed))
+		Expect(lock("test-path-1/tenant/42/project/7")).To(Equal(redsync.ErrFailed))
+		Expect(lock("test-path-1/tenant/43/project/7")).To(Succeed())
+		Expect(tenantLease.Extend()).To(BeTrue())
+
+		Expect(tenantLease.Unlock()).To(BeTrue())
+		Expect(lock("test-path-1/tenant/42/project/7")).To(Succeed())
+	})
+
+	It("excludes ancestors while a descendant is held", func() {
+		p7 := rs.NewPathMutex("test-path-2/tenant/42/project/7", redsync.NonBlocking())
+		p8 := rs.NewPathMutex("test-path-2/tenant/42/project/8", redsync.NonBlocking())
+		l7 := mustLock(p7)
+		l8 := mustLock(p8)
+		Expect(lock("test-path-2/tenant/42")).To(Equal(redsync.ErrFailed))
+		Expect(lock("test-path-2")).To(Equal(redsync.ErrFailed))
+		Expect(lock("test-path-2/tenant/42/project")).To(Equal(redsync.ErrFailed))
+		Expect(lock("test-path-2/tenant/43")).To(Succeed())
+
+		Expect(l7.Extend()).To(BeTrue())
+		Expect(l7.Unlock()).To(BeTrue())
+		Expect(lock("test-path-2/tenant/42")).To(Equal(redsync.ErrFailed))
+		Expect(l8.Unlock()).To(BeTrue())
+		Expect(lock("test-path-2/tenant/42")).To(Succeed())
+	})
+
+	It("ignores intention markers of expired descendants", func() {
+		opts := redsync.NonBlocking()
+		opts.Expiry = 50 * time.Millisecond
+		child := rs.NewPathMutex("test-path-3/a/b", opts)
+		mustLock(child)
+		Expect(lock("test-path-3/a")).To(Equal(redsync.ErrFailed))
+		Eventually(func() error { return lock("test-path-3/a") }).Should(Succeed())
+	})
+})
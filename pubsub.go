@@ -0,0 +1,93 @@
+package redsync
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// subscribe subscribes to channel on all pools,
+// and returns a channel that receives the data of every message published to it on any pool.
+// The subscriptions are confirmed before subscribe returns.
+// Pools that fail to subscribe are skipped, like nodes that fail elsewhere;
+// subscribe only returns the first error if fewer than a quorum of pools subscribed.
+// The returned channel is closed when ctx is done, or when all subscriptions fail.
+func subscribe(ctx context.Context, pools []*redis.Pool, channel string) (<-chan []byte, error) {
+	if len(pools) == 0 {
+		return nil, errors.New("redsync: no nodes to subscribe to")
+	}
+	var conns []redis.PubSubConn
+	var firstErr error
+	for _, pool := range pools {
+		psc := redis.PubSubConn{Conn: pool.Get()}
+		err := psc.Subscribe(channel)
+		if err == nil {
+			// Wait for the subscription to be confirmed, so no publishes are missed after we return.
+			if rerr, ok := psc.Receive().(error); ok {
+				err = rerr
+			}
+		}
+		if err != nil {
+			psc.Close()
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		conns = append(conns, psc)
+	}
+	if len(conns) < Quorum(len(pools)) {
+		for _, psc := range conns {
+			psc.Close()
+		}
+		return nil, firstErr
+	}
+
+	messages := make(chan []byte)
+	var wg sync.WaitGroup
+	for _, psc := range conns {
+		wg.Add(1)
+		go func(psc redis.PubSubConn) {
+			defer wg.Done()
+			// Unsubscribing from another goroutine is the only safe way to interrupt Receive.
+			stop := make(chan struct{})
+			unsubscribed := make(chan struct{})
+			go func() {
+				defer close(unsubscribed)
+				select {
+				case <-ctx.Done():
+					psc.Unsubscribe()
+				case <-stop:
+				}
+			}()
+			defer func() {
+				close(stop)
+				<-unsubscribed
+				psc.Close()
+			}()
+			for {
+				switch v := psc.Receive().(type) {
+				case redis.Message:
+					select {
+					case messages <- v.Data:
+					case <-ctx.Done():
+					}
+				case redis.Subscription:
+					if v.Count == 0 {
+						return
+					}
+				case error:
+					return
+				}
+			}
+		}(psc)
+	}
+	go func() {
+		wg.Wait()
+		close(messages)
+	}()
+
+	return messages, nil
+}
@@ -0,0 +1,60 @@
+package redsync
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Get returns the value of key that is held on a quorum of nodes,
+// or an empty string if no value is.
+// It is meant for reading keys written with Lease.SetIfHeld.
+// Nodes that fail to reply count as not holding the value,
+// but if that causes the quorum to be missed, the first error is returned.
+func (r *Redsync) Get(key string) (string, error) {
+	counts := make(map[string]int)
+	var firstErr error
+	for _, pool := range r.pools {
+		conn := pool.Get()
+		value, err := redis.String(conn.Do("GET", key))
+		conn.Close()
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		counts[value]++
+	}
+	for value, n := range counts {
+		if n >= Quorum(len(r.pools)) {
+			return value, nil
+		}
+	}
+	return "", firstErr
+}
+
+var fencedSetScript = redis.NewScript(2, `
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+	end
+	return false
+`)
+
+// fencedSet sets key to value with the given ttl on every pool where l still holds its lock,
+// and returns the number of pools it was set on.
+func fencedSet(l *Lease, key, value string, ttl time.Duration) int {
+	n := 0
+	for _, pool := range l.mutex.pools {
+		conn := pool.Get()
+		reply, err := redis.String(fencedSetScript.Do(conn, l.key, key, l.value, value, int(ttl/time.Millisecond)))
+		conn.Close()
+		if err == nil && reply == "OK" {
+			n++
+		}
+	}
+	return n
+}
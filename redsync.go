@@ -1,7 +1,6 @@
 package redsync
 
 import (
-	"fmt"
 	"github.com/gomodule/redigo/redis"
 	"time"
 )
@@ -20,7 +19,6 @@ func TcpDialer(addr string) Dialer {
 // UnixDialer connects to an address string, like "/var/folders/6j/xyz/T/abc/redis.sock".
 func UnixDialer(addr string) Dialer {
 	return func() (redis.Conn, error) {
-		fmt.Printf(addr)
 		return redis.Dial("unix", addr)
 	}
 }
@@ -29,13 +27,23 @@ func UnixDialer(addr string) Dialer {
 // It wraps a number of redis.Pool instances, each of which can have multiple connections.
 // Use NewMutex to create a mutex.
 type Redsync struct {
-	pools []*redis.Pool
+	pools    []*redis.Pool
+	registry *registry
+	queues   *localQueues
+
+	// ValueGenerator creates the tokens stored in lock keys by mutexes created after it is set,
+	// unless overridden by MutexOpts.ValueGenerator. Defaults to random tokens, like RandomValue.
+	ValueGenerator ValueGenerator
+	// ValueVerifier, if set, is used by Inspect to verify lock tokens; see LockInfo.Signer.
+	ValueVerifier ValueVerifier
 }
 
 // New creates and returns a new Redsync instance from given Redis connection pools.
 func New(pools ...*redis.Pool) *Redsync {
 	return &Redsync{
-		pools: pools,
+		pools:    pools,
+		registry: newRegistry(),
+		queues:   newLocalQueues(),
 	}
 }
 
@@ -53,6 +61,18 @@ type MutexOpts struct {
 	Delay time.Duration
 	// Factor is the clock drift Factor.
 	Factor float64
+	// Metadata describes the lock owner, and is stored alongside the lock value.
+	// It is optional; see Metadata and Redsync.Inspect.
+	Metadata *Metadata
+	// ValueGenerator creates the token stored in the lock key, such as a deterministic value in tests.
+	// Defaults to Redsync.ValueGenerator.
+	ValueGenerator ValueGenerator
+	// Local controls how goroutines of this process that lock the same name
+	// through the same Redsync coordinate; see LocalPolicy. Defaults to LocalNone.
+	// Goroutines queued locally wait for their turn for up to (Tries-1)*Delay,
+	// and each Delay they wait uses up one of their Tries in Redis,
+	// so Lock gives up after about (Tries-1)*Delay, as without queueing.
+	Local LocalPolicy
 }
 
 // Blocking returns the default MutexOpts for a blocking mutex.
@@ -81,14 +101,26 @@ func NonBlocking() MutexOpts {
 
 // NewMutex returns a new distributed mutex with given name and options.
 func (r *Redsync) NewMutex(name string, opts MutexOpts) *Mutex {
+	generate := opts.ValueGenerator
+	if generate == nil {
+		generate = r.ValueGenerator
+	}
+	if generate == nil {
+		generate = randomValue
+	}
 	return &Mutex{
-		name:   name,
-		expiry: opts.Expiry,
-		tries:  opts.Tries,
-		delay:  opts.Delay,
-		factor: opts.Factor,
-		quorum: Quorum(len(r.pools)),
-		pools:  r.pools,
+		name:     name,
+		expiry:   opts.Expiry,
+		tries:    opts.Tries,
+		delay:    opts.Delay,
+		factor:   opts.Factor,
+		owner:    opts.Metadata,
+		generate: generate,
+		quorum:   Quorum(len(r.pools)),
+		pools:    r.pools,
+		registry: r.registry,
+		local:    opts.Local,
+		queues:   r.queues,
 	}
 }
 
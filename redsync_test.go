@@ -1,10 +1,12 @@
 package redsync_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,7 +16,6 @@ import (
 	"github.com/rafaeljusto/redigomock"
 	"github.com/rgalanakis/redsync"
 	"github.com/rgalanakis/redsync/rstest"
-	"github.com/stvp/tempredis"
 )
 
 func TestRedsync(t *testing.T) {
@@ -22,17 +23,36 @@ func TestRedsync(t *testing.T) {
 	RunSpecs(t, "Redsync Suite")
 }
 
-var _ = Describe("redsync", func() {
-	tr := make(TempServers, 8)
+// tr are the redis servers shared by all specs in the suite.
+var tr = make(rstest.TempServers, 8)
 
-	BeforeSuite(func() {
-		tr.Start()
-	})
+var _ = BeforeSuite(func() {
+	tr.Start()
+})
 
-	AfterSuite(func() {
-		tr.Stop()
-	})
+var _ = AfterSuite(func() {
+	tr.Stop()
+})
+
+// locker is implemented by Mutex, MultiMutex and PathMutex.
+type locker interface {
+	Lock() (*redsync.Lease, error)
+}
+
+// mustLock locks mutex, failing the spec if it cannot.
+func mustLock(mutex locker) *redsync.Lease {
+	lease, err := mutex.Lock()
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	return lease
+}
+
+// lockErr tries to lock mutex, and returns the error.
+func lockErr(mutex locker) error {
+	_, err := mutex.Lock()
+	return err
+}
 
+var _ = Describe("redsync", func() {
 	getPoolValues := func(pools []*redis.Pool, name string) (values []string) {
 		for _, pool := range pools {
 			conn := pool.Get()
@@ -63,19 +83,11 @@ var _ = Describe("redsync", func() {
 		return n
 	}
 
-	newTestMutexes := func(pools []*redis.Pool, name string, n int) (mutexes []*redsync.Mutex) {
-		rs := redsync.New(pools...)
-		for i := 0; i < n; i++ {
-			mutexes = append(mutexes, rs.NewMutex(name, redsync.Blocking()))
-		}
-		return mutexes
-	}
-
-	assertAcquired := func(pools []*redis.Pool, mutex *redsync.Mutex) {
+	assertAcquired := func(pools []*redis.Pool, lease *redsync.Lease) {
 		n := 0
-		values := getPoolValues(pools, mutex.Name())
+		values := getPoolValues(pools, lease.Name())
 		for _, value := range values {
-			if value == mutex.Value() {
+			if value == lease.Value() {
 				n++
 			}
 		}
@@ -92,25 +104,32 @@ var _ = Describe("redsync", func() {
 			rs := redsync.New(pools...)
 
 			mutex := rs.NewMutex("test-redsync", redsync.Blocking())
-			Expect(mutex.Lock()).To(Succeed())
+			_, err := mutex.Lock()
+			Expect(err).To(Succeed())
 		})
 	})
 
 	Describe("Mutex", func() {
-		It("can acquire a lock", func() {
+		It("can acquire a lock from many goroutines sharing a Mutex", func() {
 			pools := tr.Pools(8)
-			mutexes := newTestMutexes(pools, "test-mutex", 8)
+			mutex := redsync.New(pools...).NewMutex("test-mutex", redsync.Blocking())
 			orderCh := make(chan int)
-			for i, mutex := range mutexes {
-				go func(i int, mutex *redsync.Mutex) {
-					Expect(mutex.Lock()).To(Succeed())
-					defer mutex.Unlock()
-					assertAcquired(pools, mutex)
+			held := int32(0)
+			for i := 0; i < 8; i++ {
+				go func(i int) {
+					defer GinkgoRecover()
+					lease, err := mutex.Lock()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(atomic.AddInt32(&held, 1)).To(Equal(int32(1)))
+					assertAcquired(pools, lease)
+					Expect(lease.Valid()).To(BeTrue())
+					atomic.AddInt32(&held, -1)
+					Expect(lease.Unlock()).To(BeTrue())
 
 					orderCh <- i
-				}(i, mutex)
+				}(i)
 			}
-			for range mutexes {
+			for i := 0; i < 8; i++ {
 				<-orderCh
 			}
 		})
@@ -124,11 +143,12 @@ var _ = Describe("redsync", func() {
 
 				n := clogPools(pools, mask, mutex)
 
+				lease, err := mutex.Lock()
 				if n >= len(pools)/2+1 {
-					Expect(mutex.Lock()).To(Succeed())
-					assertAcquired(pools, mutex)
+					Expect(err).To(Succeed())
+					assertAcquired(pools, lease)
 				} else {
-					Expect(mutex.Lock()).To(Equal(redsync.ErrFailed))
+					Expect(err).To(Equal(redsync.ErrFailed))
 				}
 			}
 		})
@@ -136,8 +156,8 @@ var _ = Describe("redsync", func() {
 		It("errors if all servers reply with an unexpected error", func() {
 			pools := rstest.PoolsForConn(redigomock.NewConn(), 4)
 			mutex := redsync.New(pools...).NewMutex("test-errors", redsync.NonBlocking())
-			Expect(mutex.Lock()).To(Not(Succeed()))
-			Expect(mutex.Lock().Error()).To(ContainSubstring("not registered in redigomock library"))
+			_, err := mutex.Lock()
+			Expect(err).To(MatchError(ContainSubstring("not registered in redigomock library")))
 		})
 
 		It("can use rstest to set up lock mocks", func() {
@@ -146,11 +166,98 @@ var _ = Describe("redsync", func() {
 			rstest.AddLockExpects(conn, name, "OK", nil)
 
 			pools := rstest.PoolsForConn(conn, 1)
-			mutex1 := redsync.New(pools...).NewMutex(name, redsync.NonBlocking())
-			Expect(mutex1.Lock()).To(Succeed())
+			mutex := redsync.New(pools...).NewMutex(name, redsync.NonBlocking())
+			_, err := mutex.Lock()
+			Expect(err).To(Succeed())
+
+			_, err = mutex.Lock()
+			Expect(err).To(Equal(redsync.ErrFailed))
+		})
+
+		It("can extend a held lock", func() {
+			pools := tr.Pools(3)
+			opts := redsync.NonBlocking()
+			opts.Expiry = 200 * time.Millisecond
+			lease, err := redsync.New(pools...).NewMutex("test-extend", opts).Lock()
+			Expect(err).To(Succeed())
+			until := lease.Until()
+
+			time.Sleep(150 * time.Millisecond)
+			Expect(lease.Extend()).To(BeTrue())
+			Expect(lease.Until()).To(BeTemporally(">", until))
+			time.Sleep(150 * time.Millisecond)
+			assertAcquired(pools, lease)
+			Expect(lease.Unlock()).To(BeTrue())
+			Expect(lease.Extend()).To(BeFalse())
+		})
+
+		It("reports the validity of a lease and whether it is held", func() {
+			pools := tr.Pools(3)
+			rs := redsync.New(pools...)
+			opts := redsync.NonBlocking()
+			opts.Expiry = time.Second
+			lease := mustLock(rs.NewMutex("test-isheld", opts))
+			Expect(lease.Valid()).To(BeTrue())
+			Expect(lease.Remaining()).To(BeNumerically("~", time.Second, 50*time.Millisecond))
+			Expect(lease.IsHeld(context.Background())).To(BeTrue())
+
+			// Break the lock on a majority of nodes.
+			for _, pool := range pools[:2] {
+				conn := pool.Get()
+				_, err := conn.Do("DEL", "test-isheld")
+				conn.Close()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(lease.Valid()).To(BeTrue())
+			Expect(lease.IsHeld(context.Background())).To(BeFalse())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := lease.IsHeld(ctx)
+			Expect(err).To(Equal(context.Canceled))
+		})
+
+		It("reports expired leases as not valid", func() {
+			opts := redsync.NonBlocking()
+			opts.Expiry = 50 * time.Millisecond
+			lease := mustLock(redsync.New(tr.Pools(3)...).NewMutex("test-valid-expired", opts))
+			Eventually(lease.Valid).Should(BeFalse())
+			Expect(lease.Remaining()).To(BeZero())
+			Eventually(func() (bool, error) { return lease.IsHeld(context.Background()) }).Should(BeFalse())
+		})
 
-			mutex2 := redsync.New(pools...).NewMutex(name, redsync.NonBlocking())
-			Expect(mutex2.Lock()).To(Equal(redsync.ErrFailed))
+		It("reclaims a lock with its value", func() {
+			pools := tr.Pools(3)
+			lease := mustLock(redsync.New(pools...).NewMutex("test-reclaim", redsync.NonBlocking()))
+			value := lease.Value()
+
+			// A restarted process has a new Redsync and Mutex.
+			mutex := redsync.New(pools...).NewMutex("test-reclaim", redsync.NonBlocking())
+			_, err := mutex.Reclaim("some other value")
+			Expect(err).To(Equal(redsync.ErrNotHeld))
+			reclaimed, err := mutex.Reclaim(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reclaimed.Value()).To(Equal(value))
+			Expect(reclaimed.Valid()).To(BeTrue())
+			Expect(reclaimed.Unlock()).To(BeTrue())
+
+			_, err = mutex.Reclaim(value)
+			Expect(err).To(Equal(redsync.ErrNotHeld))
+		})
+
+		It("only sets keys while the lock is held", func() {
+			pools := tr.Pools(3)
+			rs := redsync.New(pools...)
+			lease, err := rs.NewMutex("test-setifheld", redsync.NonBlocking()).Lock()
+			Expect(err).To(Succeed())
+
+			Expect(lease.SetIfHeld("test-setifheld-data", "v1", time.Minute)).To(BeTrue())
+			Expect(rs.Get("test-setifheld-data")).To(Equal("v1"))
+
+			Expect(lease.Unlock()).To(BeTrue())
+			Expect(lease.SetIfHeld("test-setifheld-data", "v2", time.Minute)).To(BeFalse())
+			Expect(rs.Get("test-setifheld-data")).To(Equal("v1"))
+			Expect(rs.Get("test-setifheld-missing")).To(Equal(""))
 		})
 
 		It("will conditionally execute a function on lock acquisition", func() {
@@ -223,71 +330,3 @@ var _ = Describe("redsync", func() {
 	})
 
 })
-
-type TempServers []*tempredis.Server
-
-// Start starts the tempredis servers and fills in the empty slice.
-func (ts TempServers) Start() {
-	for i := 0; i < len(ts); i++ {
-		server, err := tempredis.Start(tempredis.Config{})
-		if err != nil {
-			panic(err)
-		}
-		ts[i] = server
-	}
-}
-
-// Pools returns a slice of redis.Pool instances, one for each server, up to n.
-func (ts TempServers) Pools(n int) []*redis.Pool {
-	var pools []*redis.Pool
-	for _, server := range ts {
-		func(server *tempredis.Server) {
-			pools = append(pools, &redis.Pool{
-				MaxIdle:     3,
-				IdleTimeout: 240 * time.Second,
-				Dial:        redsync.UnixDialer(server.Socket()),
-				TestOnBorrow: func(c redis.Conn, t time.Time) error {
-					_, err := c.Do("PING")
-					return err
-				},
-			})
-		}(server)
-		if len(pools) == n {
-			break
-		}
-	}
-	return pools
-}
-
-// Stop stops the testredis servers.
-func (ts TempServers) Stop() {
-	for _, server := range ts {
-		server.Term()
-	}
-}
-
-// TempServers is a slice of tempredis reservers.
-// Create a TempServers slice of the length equal to the number of servers,
-// then use Start() to fill it with servers. Stop() stops the servers.
-// Pool(n) returns a slice of redis.Pool instances, one for each server,
-// up to n.
-func ExampleServers() {
-	tr := make(TempServers, 2)
-	fmt.Println("Created", len(tr), "temp redis servers")
-	fmt.Println("TempServers are nil?", tr[0] == nil)
-	tr.Start()
-	fmt.Println("Started servers")
-	fmt.Println("TempServers are nil?", tr[0] == nil)
-
-	pools := tr.Pools(2)
-	fmt.Println("Created a slice of", len(pools), "[]*redis.Pool, each to a different server")
-	tr.Stop()
-	fmt.Println("TempServers terminated")
-	// Output:
-	// Created 2 temp redis servers
-	// TempServers are nil? true
-	// Started servers
-	// TempServers are nil? false
-	// Created a slice of 2 []*redis.Pool, each to a different server
-	// TempServers terminated
-}
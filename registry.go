@@ -0,0 +1,162 @@
+package redsync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrClosed is returned when locking through a Redsync that was closed.
+var ErrClosed = errors.New("redsync: closed")
+
+// registry tracks the leases held through a Redsync.
+// Leases that expired without being unlocked are pruned as it grows.
+type registry struct {
+	mu     sync.Mutex
+	leases map[*Lease]struct{}
+	// pruneAt is the number of leases at which expired leases are pruned.
+	pruneAt int
+	closed  bool
+}
+
+func newRegistry() *registry {
+	return &registry{leases: make(map[*Lease]struct{}), pruneAt: 64}
+}
+
+// add adds l, and returns false if the registry is closed.
+func (g *registry) add(l *Lease) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	g.leases[l] = struct{}{}
+	if len(g.leases) >= g.pruneAt {
+		for held := range g.leases {
+			if !held.Valid() {
+				delete(g.leases, held)
+			}
+		}
+		g.pruneAt = 2 * len(g.leases)
+		if g.pruneAt < 64 {
+			g.pruneAt = 64
+		}
+	}
+	return true
+}
+
+func (g *registry) remove(l *Lease) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.leases, l)
+}
+
+func (g *registry) all() []*Lease {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	leases := make([]*Lease, 0, len(g.leases))
+	for l := range g.leases {
+		leases = append(leases, l)
+	}
+	return leases
+}
+
+func (g *registry) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// Held returns the leases acquired through the Redsync that were not unlocked yet.
+// Some of them may have expired.
+func (r *Redsync) Held() []*Lease {
+	return r.registry.all()
+}
+
+// ReleaseAll unlocks all leases acquired through the Redsync, in parallel,
+// and stops their KeepAlive goroutines.
+// It returns ctx.Err() if ctx is done before all leases are unlocked;
+// the remaining leases are still unlocked in the background.
+// Failures to unlock are ignored, since the locks expire anyway.
+func (r *Redsync) ReleaseAll(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, l := range r.registry.all() {
+		wg.Add(1)
+		go func(l *Lease) {
+			defer wg.Done()
+			l.Unlock()
+		}(l)
+	}
+	released := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(released)
+	}()
+	select {
+	case <-released:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close releases all leases like ReleaseAll, and makes further attempts to lock
+// through the Redsync fail with ErrClosed.
+// Use it when a process shuts down, so other processes do not have to wait for its locks to expire.
+func (r *Redsync) Close(ctx context.Context) error {
+	r.registry.close()
+	return r.ReleaseAll(ctx)
+}
+
+// CloseOnSignal calls Close, with the given timeout, when the process receives one of sigs,
+// which default to SIGTERM and SIGINT.
+// The signal is then raised again with CloseOnSignal's handler removed,
+// so the process exits as it would have without the handler.
+// Go cannot tell whether the process handles the signal itself with signal.Notify;
+// if it does, it receives the signal twice, so use CloseOnSignalNoReraise instead.
+// The returned function removes the handler.
+func (r *Redsync) CloseOnSignal(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	return r.closeOnSignal(timeout, true, sigs)
+}
+
+// CloseOnSignalNoReraise is like CloseOnSignal, but does not raise the signal again after closing,
+// for processes that handle sigs themselves, and exit when they do.
+func (r *Redsync) CloseOnSignalNoReraise(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	return r.closeOnSignal(timeout, false, sigs)
+}
+
+func (r *Redsync) closeOnSignal(timeout time.Duration, reraise bool, sigs []os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+	go func() {
+		select {
+		case sig := <-c:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			r.Close(ctx)
+			cancel()
+			signal.Stop(c)
+			if !reraise {
+				return
+			}
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
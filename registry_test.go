@@ -0,0 +1,80 @@
+package redsync_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Registry", func() {
+	It("releases all held leases and stops their keepalives", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		a := mustLock(rs.NewMutex("test-registry-a", redsync.NonBlocking()))
+		b := mustLock(rs.NewMultiMutex([]string{"test-registry-b", "test-registry-c"}, redsync.NonBlocking()))
+		c := mustLock(rs.NewMutex("test-registry-d", redsync.NonBlocking()))
+		Expect(c.Unlock()).To(BeTrue())
+		Expect(rs.Held()).To(ConsistOf(a, b))
+
+		stopped := a.KeepAlive(make(chan struct{}))
+		Expect(rs.ReleaseAll(context.Background())).To(Succeed())
+		Eventually(stopped).Should(BeClosed())
+		Expect(rs.Held()).To(BeEmpty())
+		Expect(a.IsHeld(context.Background())).To(BeFalse())
+		Expect(b.IsHeld(context.Background())).To(BeFalse())
+
+		// ReleaseAll does not prevent further locking.
+		Expect(mustLock(rs.NewMutex("test-registry-a", redsync.NonBlocking())).Unlock()).To(BeTrue())
+	})
+
+	It("fails to lock after Close", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		lease := mustLock(rs.NewMutex("test-registry-close", redsync.NonBlocking()))
+		Expect(rs.Close(context.Background())).To(Succeed())
+		Expect(lockErr(rs.NewMutex("test-registry-close", redsync.NonBlocking()))).To(Equal(redsync.ErrClosed))
+		_, err := rs.NewMutex("test-registry-close", redsync.NonBlocking()).Reclaim(lease.Value())
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+
+		other := redsync.New(pools...)
+		Expect(mustLock(other.NewMutex("test-registry-close", redsync.NonBlocking())).Unlock()).To(BeTrue())
+	})
+
+	It("closes on a signal", func() {
+		// Handle the signal here too, like a process that handles it itself.
+		sigs := make(chan os.Signal, 2)
+		signal.Notify(sigs, syscall.SIGUSR1)
+		defer signal.Stop(sigs)
+
+		rs := redsync.New(tr.Pools(3)...)
+		stop := rs.CloseOnSignalNoReraise(time.Second, syscall.SIGUSR1)
+		defer stop()
+		mustLock(rs.NewMutex("test-registry-signal", redsync.NonBlocking()))
+
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR1)).To(Succeed())
+		Eventually(rs.Held).Should(BeEmpty())
+		Eventually(func() error { return lockErr(rs.NewMutex("test-registry-signal", redsync.NonBlocking())) }).Should(Equal(redsync.ErrClosed))
+		Consistently(sigs, 100*time.Millisecond).Should(HaveLen(1))
+	})
+
+	It("raises the signal again after closing", func() {
+		// Handle the signal here too, so it does not kill the test process when it is raised again.
+		sigs := make(chan os.Signal, 2)
+		signal.Notify(sigs, syscall.SIGUSR2)
+		defer signal.Stop(sigs)
+
+		rs := redsync.New(tr.Pools(3)...)
+		stop := rs.CloseOnSignal(time.Second, syscall.SIGUSR2)
+		defer stop()
+
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR2)).To(Succeed())
+		Eventually(func() error { return lockErr(rs.NewMutex("test-registry-reraise", redsync.NonBlocking())) }).Should(Equal(redsync.ErrClosed))
+		Eventually(sigs).Should(HaveLen(2))
+	})
+})
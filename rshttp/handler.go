@@ -0,0 +1,226 @@
+// Package rshttp provides an http.Handler exposing the state of Redsync locks as JSON.
+//
+// It is meant to be mounted on an internal debug port, next to pprof,
+// so incident responders can see lock state without shell access to Redis:
+//
+//	http.Handle("/debug/locks/", http.StripPrefix("/debug/locks", rshttp.NewHandler(rs, rshttp.Opts{})))
+//
+// The handler serves the following endpoints, relative to where it is mounted:
+//
+//	GET    /locks?pattern=P       status of all locks matching P (default Opts.Pattern)
+//	GET    /lock?name=N           holder, metadata and TTL of lock N on each node
+//	DELETE /lock?name=N[&value=V] force-unlock lock N (only if Opts.AllowForceUnlock is set)
+//	GET    /nodes                 health of each node
+//
+// Lock names are passed as query parameters, so they may contain slashes.
+package rshttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rgalanakis/redsync"
+)
+
+// Opts are the options for NewHandler.
+type Opts struct {
+	// AllowForceUnlock enables force-unlocking locks with DELETE /lock.
+	// It is off by default, since anyone who can reach the handler could then break locks.
+	AllowForceUnlock bool
+	// Pattern is the pattern used by GET /locks when the request does not give one.
+	// Defaults to "*". Set it if lock names share a prefix, to avoid listing unrelated keys.
+	Pattern string
+}
+
+// Handler serves lock state as JSON. Use NewHandler to create one.
+type Handler struct {
+	rs   *redsync.Redsync
+	opts Opts
+}
+
+// NewHandler returns a Handler reporting on the locks of rs.
+func NewHandler(rs *redsync.Redsync, opts Opts) *Handler {
+	if opts.Pattern == "" {
+		opts.Pattern = "*"
+	}
+	return &Handler{rs: rs, opts: opts}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/locks" && r.Method == http.MethodGet:
+		h.serveList(w, r)
+	case r.URL.Path == "/lock" && r.Method == http.MethodGet:
+		h.serveInspect(w, r)
+	case r.URL.Path == "/lock" && r.Method == http.MethodDelete:
+		h.serveForceUnlock(w, r)
+	case r.URL.Path == "/nodes" && r.Method == http.MethodGet:
+		h.serveNodes(w, r)
+	case r.URL.Path == "/locks" || r.URL.Path == "/lock" || r.URL.Path == "/nodes":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+type lockStatus struct {
+	Name       string            `json:"name"`
+	Nodes      int               `json:"nodes"`
+	Held       int               `json:"held"`
+	Agree      int               `json:"agree"`
+	Value      string            `json:"value,omitempty"`
+	Owner      *redsync.Metadata `json:"owner,omitempty"`
+	MinTTLMs   int64             `json:"min_ttl_ms"`
+	MaxTTLMs   int64             `json:"max_ttl_ms"`
+	Quorum     bool              `json:"quorum"`
+	SplitBrain bool              `json:"split_brain"`
+}
+
+type nodeLock struct {
+	Node  int               `json:"node"`
+	Value string            `json:"value,omitempty"`
+	Owner *redsync.Metadata `json:"owner,omitempty"`
+	TTLMs int64             `json:"ttl_ms"`
+	Error string            `json:"error,omitempty"`
+}
+
+type lockDetail struct {
+	lockStatus
+	PerNode []nodeLock `json:"per_node"`
+}
+
+type nodeResult struct {
+	Node    int    `json:"node"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+type nodeHealth struct {
+	Node      int     `json:"node"`
+	Healthy   bool    `json:"healthy"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func (h *Handler) serveList(w http.ResponseWriter, r *http.Request) {
+	pattern := r.URL.Query().Get("pattern")
+	if pattern == "" {
+		pattern = h.opts.Pattern
+	}
+	statuses, err := h.rs.List(r.Context(), pattern)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	result := make([]lockStatus, len(statuses))
+	for i, s := range statuses {
+		result[i] = newLockStatus(s)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) serveInspect(w http.ResponseWriter, r *http.Request) {
+	name, ok := requireName(w, r)
+	if !ok {
+		return
+	}
+	statuses, err := h.rs.Status(name)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	detail := lockDetail{lockStatus: newLockStatus(statuses[0])}
+	for _, info := range h.rs.InspectNodes(name) {
+		detail.PerNode = append(detail.PerNode, nodeLock{
+			Node:  info.Node,
+			Value: info.Value,
+			Owner: info.Metadata,
+			TTLMs: milliseconds(info.TTL),
+			Error: errorString(info.Err),
+		})
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h *Handler) serveForceUnlock(w http.ResponseWriter, r *http.Request) {
+	if !h.opts.AllowForceUnlock {
+		writeError(w, http.StatusForbidden, "force-unlock is disabled")
+		return
+	}
+	name, ok := requireName(w, r)
+	if !ok {
+		return
+	}
+	var results []redsync.NodeResult
+	if value := r.URL.Query().Get("value"); value != "" {
+		results = h.rs.ForceUnlockIf(name, value)
+	} else {
+		results = h.rs.ForceUnlock(name)
+	}
+	result := make([]nodeResult, len(results))
+	for i, res := range results {
+		result[i] = nodeResult{Node: res.Node, Deleted: res.Deleted, Error: errorString(res.Err)}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) serveNodes(w http.ResponseWriter, r *http.Request) {
+	health := h.rs.Ping()
+	result := make([]nodeHealth, len(health))
+	for i, nh := range health {
+		result[i] = nodeHealth{
+			Node:      nh.Node,
+			Healthy:   nh.Err == nil,
+			LatencyMs: float64(nh.Latency) / float64(time.Millisecond),
+			Error:     errorString(nh.Err),
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func newLockStatus(s redsync.LockStatus) lockStatus {
+	return lockStatus{
+		Name:       s.Name,
+		Nodes:      s.Nodes,
+		Held:       s.Held,
+		Agree:      s.Agree,
+		Value:      s.Value,
+		Owner:      s.Metadata,
+		MinTTLMs:   milliseconds(s.MinTTL),
+		MaxTTLMs:   milliseconds(s.MaxTTL),
+		Quorum:     s.HasQuorum(),
+		SplitBrain: s.SplitBrain(),
+	}
+}
+
+func requireName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return "", false
+	}
+	return name, true
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
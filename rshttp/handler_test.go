@@ -0,0 +1,139 @@
+package rshttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+	"github.com/rgalanakis/redsync/rshttp"
+	"github.com/rgalanakis/redsync/rstest"
+)
+
+func TestRshttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "rshttp Suite")
+}
+
+var tr = make(rstest.TempServers, 3)
+
+var _ = BeforeSuite(func() {
+	tr.Start()
+})
+
+var _ = AfterSuite(func() {
+	tr.Stop()
+})
+
+var _ = Describe("Handler", func() {
+	var rs *redsync.Redsync
+
+	BeforeEach(func() {
+		rs = redsync.New(tr.Pools(3)...)
+	})
+
+	serve := func(h http.Handler, method, target string, into interface{}) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+		if into != nil {
+			Expect(json.Unmarshal(w.Body.Bytes(), into)).To(Succeed())
+		}
+		return w.Code
+	}
+
+	lock := func(name string) *redsync.Lease {
+		opts := redsync.NonBlocking()
+		opts.Metadata = redsync.ProcessMetadata("rshttp-test")
+		lease, err := rs.NewMutex(name, opts).Lock()
+		Expect(err).ToNot(HaveOccurred())
+		return lease
+	}
+
+	It("lists locks matching a pattern", func() {
+		defer lock("rshttp-list/a").Unlock()
+		defer lock("rshttp-list/b").Unlock()
+
+		var body []map[string]interface{}
+		h := rshttp.NewHandler(rs, rshttp.Opts{})
+		Expect(serve(h, "GET", "/locks?pattern=rshttp-list/*", &body)).To(Equal(http.StatusOK))
+		Expect(body).To(HaveLen(2))
+		Expect(body[0]).To(HaveKeyWithValue("name", "rshttp-list/a"))
+		Expect(body[0]).To(HaveKeyWithValue("held", BeNumerically("==", 3)))
+		Expect(body[0]).To(HaveKeyWithValue("quorum", true))
+		Expect(body[0]).To(HaveKeyWithValue("owner", HaveKeyWithValue("service", "rshttp-test")))
+	})
+
+	It("uses the default pattern", func() {
+		defer lock("rshttp-default").Unlock()
+
+		var body []map[string]interface{}
+		h := rshttp.NewHandler(rs, rshttp.Opts{Pattern: "rshttp-default*"})
+		Expect(serve(h, "GET", "/locks", &body)).To(Equal(http.StatusOK))
+		Expect(body).To(HaveLen(1))
+	})
+
+	It("inspects a lock on each node", func() {
+		lease := lock("rshttp-inspect")
+		defer lease.Unlock()
+
+		var body map[string]interface{}
+		h := rshttp.NewHandler(rs, rshttp.Opts{})
+		Expect(serve(h, "GET", "/lock?name=rshttp-inspect", &body)).To(Equal(http.StatusOK))
+		Expect(body).To(HaveKeyWithValue("name", "rshttp-inspect"))
+		Expect(body).To(HaveKeyWithValue("value", lease.Value()))
+		Expect(body["per_node"]).To(HaveLen(3))
+		node := body["per_node"].([]interface{})[1].(map[string]interface{})
+		Expect(node).To(HaveKeyWithValue("node", BeNumerically("==", 1)))
+		Expect(node).To(HaveKeyWithValue("value", lease.Value()))
+		Expect(node).To(HaveKeyWithValue("ttl_ms", BeNumerically(">", 0)))
+		Expect(node).To(HaveKeyWithValue("owner", HaveKeyWithValue("service", "rshttp-test")))
+
+		Expect(serve(h, "GET", "/lock", &body)).To(Equal(http.StatusBadRequest))
+		Expect(body).To(HaveKeyWithValue("error", "name is required"))
+	})
+
+	It("only force-unlocks when allowed", func() {
+		lock("rshttp-force")
+
+		var body interface{}
+		Expect(serve(rshttp.NewHandler(rs, rshttp.Opts{}), "DELETE", "/lock?name=rshttp-force", &body)).
+			To(Equal(http.StatusForbidden))
+		_, err := rs.Inspect("rshttp-force")
+		Expect(err).ToNot(HaveOccurred())
+
+		var results []map[string]interface{}
+		h := rshttp.NewHandler(rs, rshttp.Opts{AllowForceUnlock: true})
+		Expect(serve(h, "DELETE", "/lock?name=rshttp-force&value=other", &results)).To(Equal(http.StatusOK))
+		Expect(results).To(HaveLen(3))
+		Expect(results[0]).To(HaveKeyWithValue("deleted", false))
+
+		Expect(serve(h, "DELETE", "/lock?name=rshttp-force", &results)).To(Equal(http.StatusOK))
+		Expect(results[0]).To(HaveKeyWithValue("deleted", true))
+		_, err = rs.Inspect("rshttp-force")
+		Expect(err).To(Equal(redsync.ErrNotHeld))
+	})
+
+	It("reports node health", func() {
+		pools := append(tr.Pools(1), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		h := rshttp.NewHandler(redsync.New(pools...), rshttp.Opts{})
+
+		var body []map[string]interface{}
+		Expect(serve(h, "GET", "/nodes", &body)).To(Equal(http.StatusOK))
+		Expect(body).To(HaveLen(2))
+		Expect(body[0]).To(HaveKeyWithValue("healthy", true))
+		Expect(body[0]).ToNot(HaveKey("error"))
+		Expect(body[1]).To(HaveKeyWithValue("healthy", false))
+		Expect(body[1]).To(HaveKeyWithValue("error", ContainSubstring("connection refused")))
+	})
+
+	It("rejects unknown paths and methods", func() {
+		h := rshttp.NewHandler(rs, rshttp.Opts{})
+		Expect(serve(h, "GET", "/unknown", nil)).To(Equal(http.StatusNotFound))
+		Expect(serve(h, "POST", "/locks", nil)).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
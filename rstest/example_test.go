@@ -16,11 +16,16 @@ func ExampleAddLockExpects() {
 	pools := rstest.PoolsForConn(conn, 1)
 	mutex := redsync.New(pools...).NewMutex("example-lock-expects", redsync.NonBlocking())
 
-	fmt.Println(mutex.Lock())
-	fmt.Println(mutex.Lock())
-	fmt.Println(mutex.Lock())
-	fmt.Println(mutex.Lock())
-	fmt.Println(mutex.Lock())
+	lock := func() error {
+		_, err := mutex.Lock()
+		return err
+	}
+
+	fmt.Println(lock())
+	fmt.Println(lock())
+	fmt.Println(lock())
+	fmt.Println(lock())
+	fmt.Println(lock())
 	// Output:
 	// <nil>
 	// <nil>
@@ -29,3 +34,28 @@ func ExampleAddLockExpects() {
 	// uh-oh
 
 }
+
+// Create a TempServers slice of the length equal to the number of servers,
+// then use Start() to fill it with servers. Stop() stops the servers.
+// Pool(n) returns a slice of redis.Pool instances, one for each server,
+// up to n.
+func ExampleTempServers() {
+	tr := make(rstest.TempServers, 2)
+	fmt.Println("Created", len(tr), "temp redis servers")
+	fmt.Println("TempServers are nil?", tr[0] == nil)
+	tr.Start()
+	fmt.Println("Started servers")
+	fmt.Println("TempServers are nil?", tr[0] == nil)
+
+	pools := tr.Pools(2)
+	fmt.Println("Created a slice of", len(pools), "[]*redis.Pool, each to a different server")
+	tr.Stop()
+	fmt.Println("TempServers terminated")
+	// Output:
+	// Created 2 temp redis servers
+	// TempServers are nil? true
+	// Started servers
+	// TempServers are nil? false
+	// Created a slice of 2 []*redis.Pool, each to a different server
+	// TempServers terminated
+}
@@ -0,0 +1,275 @@
+// Package scheduler runs scheduled jobs on exactly one of a set of replicas.
+//
+// Every replica registers the same jobs and calls Run. For each scheduled time (tick) of a job,
+// the replicas race for a lock named after the job and the tick; the winner runs the job,
+// extending the lock for as long as the job runs, and records the outcome.
+// Because the lock is per tick, a long-running job never causes the next tick to be skipped
+// or run twice, unlike a ticker around a single lock with a fixed expiry.
+//
+//	s := scheduler.New(rs, scheduler.Opts{})
+//	s.Add("nightly-report", scheduler.MustCron("0 3 * * *"), generateReport)
+//	s.Add("refresh-cache", scheduler.Every(5*time.Minute), refreshCache)
+//	s.Run(ctx)
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rgalanakis/redsync"
+)
+
+// Job is a function run by the scheduler.
+// The context is canceled if the scheduler is stopped, or if the job's lock is lost.
+type Job func(ctx context.Context) error
+
+// Opts are the options for a Scheduler.
+type Opts struct {
+	// Prefix is prepended to the job name to get the names of lock and record keys.
+	// Defaults to "scheduler:".
+	Prefix string
+	// Expiry is the expiry of a tick's lock. The lock is extended while the job runs,
+	// so this only matters if the process dies. Defaults to 8s.
+	Expiry time.Duration
+	// RecordTTL is how long the records of a job's runs are kept. Defaults to 7 days.
+	RecordTTL time.Duration
+	// Identity is stored in tick locks and run records, to identify the replica running a job.
+	// Defaults to redsync.ProcessMetadata("scheduler").
+	Identity *redsync.Metadata
+	// OnRun is called after this replica runs a job. It is optional.
+	OnRun func(Run)
+	// OnError is called when a job cannot be run or recorded due to an error talking to Redis.
+	// It is optional.
+	OnError func(job string, err error)
+}
+
+// Run is the record of a job run.
+type Run struct {
+	// Job is the name of the job.
+	Job string `json:"job"`
+	// Scheduled is the tick the run was for.
+	Scheduled time.Time `json:"scheduled"`
+	// Started and Finished are the times the job started and finished running.
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Error is the error message of the job, or empty if it succeeded.
+	Error string `json:"error,omitempty"`
+	// Runner identifies the replica that ran the job.
+	Runner *redsync.Metadata `json:"runner,omitempty"`
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	fn       Job
+}
+
+// Scheduler runs jobs on schedule, coordinating with other replicas so each tick runs once.
+type Scheduler struct {
+	rs   *redsync.Redsync
+	opts Opts
+
+	mu      sync.Mutex
+	jobs    []*job
+	running bool
+}
+
+// New returns a Scheduler using the locks of rs.
+func New(rs *redsync.Redsync, opts Opts) *Scheduler {
+	if opts.Prefix == "" {
+		opts.Prefix = "scheduler:"
+	}
+	if opts.Expiry == 0 {
+		opts.Expiry = 8 * time.Second
+	}
+	if opts.RecordTTL == 0 {
+		opts.RecordTTL = 7 * 24 * time.Hour
+	}
+	if opts.Identity == nil {
+		opts.Identity = redsync.ProcessMetadata("scheduler")
+	}
+	return &Scheduler{rs: rs, opts: opts}
+}
+
+// Add registers a job. Job names must be unique, and the same on every replica.
+// Jobs must be added before Run is called.
+func (s *Scheduler) Add(name string, schedule Schedule, fn Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return errors.New("scheduler: cannot add jobs while running")
+	}
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("scheduler: job %q already added", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, schedule: schedule, fn: fn})
+	return nil
+}
+
+// Run runs jobs on schedule until ctx is done, then waits for running jobs to return.
+// Ticks that pass while no replica is running are skipped.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("scheduler: already running")
+	}
+	s.running = true
+	jobs := s.jobs
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// loop waits for each tick of j and tries to run it.
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	var running sync.WaitGroup
+	defer running.Wait()
+	for {
+		tick := j.schedule.Next(time.Now())
+		if tick.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(tick))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		// Run in the background, so a long-running job does not delay its next tick.
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			s.runTick(ctx, j, tick)
+		}()
+	}
+}
+
+// runTick runs j for tick, if this replica wins the tick's lock
+// and the tick has not already been run.
+func (s *Scheduler) runTick(ctx context.Context, j *job, tick time.Time) {
+	opts := redsync.NonBlocking()
+	opts.Expiry = s.opts.Expiry
+	opts.Metadata = s.opts.Identity
+	lease, err := s.rs.NewMutex(s.lockName(j.name, tick), opts).Lock()
+	if err != nil {
+		if err != redsync.ErrFailed {
+			s.onError(j.name, err)
+		}
+		return
+	}
+	// The tick lock is not released, but left to expire,
+	// so replicas arriving late cannot take it while the job is still fresh.
+	// Replicas arriving even later see the run record of the tick, or of a later tick, and skip it.
+	// Runs of different ticks overlap, so the last run record may be for an earlier tick.
+	ran, err := s.rs.Get(s.tickRecordKey(j.name, tick))
+	if err != nil {
+		s.onError(j.name, err)
+		return
+	}
+	if ran != "" {
+		return
+	}
+	last, err := s.LastRun(j.name)
+	if err != nil {
+		s.onError(j.name, err)
+		return
+	}
+	if last != nil && !last.Scheduled.Before(tick) {
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := make(chan struct{})
+	stopped := lease.KeepAlive(stop)
+	go func() {
+		select {
+		case <-stopped:
+			// The lock was lost, or the job finished.
+			cancel()
+		case <-jobCtx.Done():
+		}
+	}()
+
+	run := Run{Job: j.name, Scheduled: tick, Started: time.Now(), Runner: s.opts.Identity}
+	if err := j.fn(jobCtx); err != nil {
+		run.Error = err.Error()
+	}
+	run.Finished = time.Now()
+	close(stop)
+	<-stopped
+
+	b, err := json.Marshal(run)
+	if err != nil {
+		s.onError(j.name, err)
+		return
+	}
+	if !lease.SetIfHeld(s.tickRecordKey(j.name, tick), string(b), s.opts.RecordTTL) {
+		s.onError(j.name, redsync.ErrNotHeld)
+	}
+	// Do not replace the record of a later tick that finished first.
+	if last, err := s.LastRun(j.name); err != nil {
+		s.onError(j.name, err)
+	} else if last == nil || last.Scheduled.Before(tick) {
+		lease.SetIfHeld(s.recordKey(j.name), string(b), s.opts.RecordTTL)
+	}
+	if s.opts.OnRun != nil {
+		s.opts.OnRun(run)
+	}
+}
+
+// LastRun returns the record of the last run of the named job, on any replica,
+// or nil if it has not run within Opts.RecordTTL.
+func (s *Scheduler) LastRun(name string) (*Run, error) {
+	value, err := s.rs.Get(s.recordKey(name))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	run := &Run{}
+	if err := json.Unmarshal([]byte(value), run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *Scheduler) lockName(name string, tick time.Time) string {
+	return s.opts.Prefix + name + ":" + tick.UTC().Format(time.RFC3339Nano)
+}
+
+func (s *Scheduler) recordKey(name string) string {
+	return s.opts.Prefix + name + ":last"
+}
+
+// tickRecordKey is the key of the record of the run for tick,
+// which is kept even if the record of the last run is for a later tick.
+func (s *Scheduler) tickRecordKey(name string, tick time.Time) string {
+	return s.opts.Prefix + name + ":run:" + tick.UTC().Format(time.RFC3339Nano)
+}
+
+func (s *Scheduler) onError(name string, err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(name, err)
+	}
+}
@@ -0,0 +1,239 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+	"github.com/rgalanakis/redsync/rstest"
+	"github.com/rgalanakis/redsync/scheduler"
+)
+
+func TestScheduler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Scheduler Suite")
+}
+
+var tr = make(rstest.TempServers, 3)
+
+var _ = BeforeSuite(func() {
+	tr.Start()
+})
+
+var _ = AfterSuite(func() {
+	tr.Stop()
+})
+
+// once is a Schedule with a single tick, which may be in the past.
+type once struct {
+	tick time.Time
+	done bool
+}
+
+func (o *once) Next(t time.Time) time.Time {
+	if o.done {
+		return time.Time{}
+	}
+	o.done = true
+	return o.tick
+}
+
+func mustTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+var _ = Describe("Schedule", func() {
+	It("runs Every at multiples of the interval", func() {
+		s := scheduler.Every(15 * time.Minute)
+		Expect(s.Next(mustTime("2018-03-04T10:07:30Z"))).To(Equal(mustTime("2018-03-04T10:15:00Z")))
+		Expect(s.Next(mustTime("2018-03-04T10:15:00Z"))).To(Equal(mustTime("2018-03-04T10:30:00Z")))
+	})
+
+	It("runs Cron at matching times", func() {
+		cases := [][3]string{
+			{"* * * * *", "2018-03-04T10:07:30Z", "2018-03-04T10:08:00Z"},
+			{"*/15 * * * *", "2018-03-04T10:07:00Z", "2018-03-04T10:15:00Z"},
+			{"0-30/10 9-17 * * *", "2018-03-04T17:31:00Z", "2018-03-05T09:00:00Z"},
+			{"0 3,15 * * *", "2018-03-04T03:00:00Z", "2018-03-04T15:00:00Z"},
+			{"0 0 1 * *", "2018-01-15T00:00:00Z", "2018-02-01T00:00:00Z"},
+			{"30 8 * * 1-5", "2018-03-03T09:00:00Z", "2018-03-05T08:30:00Z"},
+			{"0 0 * * 7", "2018-03-05T00:00:00Z", "2018-03-11T00:00:00Z"},
+			{"0 0 13 * 5", "2018-03-03T00:00:00Z", "2018-03-09T00:00:00Z"},
+			{"0 0 */2 * 1", "2018-03-05T00:00:00Z", "2018-03-19T00:00:00Z"},
+			{"0 0 29 2 *", "2018-03-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+			{"@hourly", "2018-03-04T10:07:00Z", "2018-03-04T11:00:00Z"},
+		}
+		for _, c := range cases {
+			s, err := scheduler.Cron(c[0])
+			Expect(err).ToNot(HaveOccurred(), c[0])
+			Expect(s.Next(mustTime(c[1]))).To(Equal(mustTime(c[2])), c[0])
+		}
+	})
+
+	It("never runs impossible cron expressions", func() {
+		Expect(scheduler.MustCron("0 0 30 2 *").Next(time.Now()).IsZero()).To(BeTrue())
+	})
+
+	It("rejects invalid cron expressions", func() {
+		for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "a * * * *", "*/0 * * * *", "5-1 * * * *"} {
+			_, err := scheduler.Cron(spec)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+})
+
+var _ = Describe("Scheduler", func() {
+	var rs *redsync.Redsync
+
+	BeforeEach(func() {
+		rs = redsync.New(tr.Pools(3)...)
+	})
+
+	It("runs each tick on exactly one replica", func() {
+		var mu sync.Mutex
+		ticks := make(map[time.Time]int)
+		var replicas []*scheduler.Scheduler
+		for i := 0; i < 3; i++ {
+			s := scheduler.New(rs, scheduler.Opts{
+				Prefix: "test-scheduler-once:",
+				Expiry: 100 * time.Millisecond,
+				OnRun: func(run scheduler.Run) {
+					mu.Lock()
+					ticks[run.Scheduled]++
+					mu.Unlock()
+				},
+			})
+			// Jobs outlive both their interval and the lock expiry.
+			Expect(s.Add("job", scheduler.Every(50*time.Millisecond), func(ctx context.Context) error {
+				time.Sleep(120 * time.Millisecond)
+				return nil
+			})).To(Succeed())
+			replicas = append(replicas, s)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 520*time.Millisecond)
+		defer cancel()
+		var wg sync.WaitGroup
+		for _, s := range replicas {
+			wg.Add(1)
+			go func(s *scheduler.Scheduler) {
+				defer wg.Done()
+				s.Run(ctx)
+			}(s)
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(len(ticks)).To(BeNumerically(">=", 8))
+		for tick, n := range ticks {
+			Expect(n).To(Equal(1), tick.String())
+		}
+	})
+
+	It("records the last run", func() {
+		s := scheduler.New(rs, scheduler.Opts{
+			Prefix:   "test-scheduler-record:",
+			Identity: &redsync.Metadata{Service: "reporter"},
+		})
+		Expect(s.Add("failing", scheduler.Every(20*time.Millisecond), func(ctx context.Context) error {
+			return errors.New("no data")
+		})).To(Succeed())
+		Expect(s.Add("failing", scheduler.Every(time.Second), nil)).ToNot(Succeed())
+		run, err := s.LastRun("failing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(run).To(BeNil())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+		defer cancel()
+		Expect(s.Run(ctx)).To(Equal(context.DeadlineExceeded))
+
+		run, err = s.LastRun("failing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(run.Job).To(Equal("failing"))
+		Expect(run.Error).To(Equal("no data"))
+		Expect(run.Runner.Service).To(Equal("reporter"))
+		Expect(run.Finished).To(BeTemporally(">=", run.Started))
+		Expect(run.Scheduled).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
+	})
+
+	It("does not run a tick again when an earlier tick finishes after it", func() {
+		var mu sync.Mutex
+		var ticks []time.Time
+		onRun := func(run scheduler.Run) {
+			mu.Lock()
+			defer mu.Unlock()
+			ticks = append(ticks, run.Scheduled)
+		}
+		opts := scheduler.Opts{Prefix: "test-scheduler-overlap:", Expiry: 50 * time.Millisecond, OnRun: onRun}
+		s := scheduler.New(rs, opts)
+		var calls int32
+		// The first tick finishes after all the others.
+		Expect(s.Add("job", scheduler.Every(40*time.Millisecond), func(ctx context.Context) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return nil
+		})).To(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 130*time.Millisecond)
+		defer cancel()
+		s.Run(ctx)
+
+		mu.Lock()
+		Expect(len(ticks)).To(BeNumerically(">=", 2))
+		latest := ticks[0]
+		for _, tick := range ticks {
+			if tick.After(latest) {
+				latest = tick
+			}
+		}
+		mu.Unlock()
+		run, err := s.LastRun("job")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(run.Scheduled).To(BeTemporally("==", latest))
+
+		// A replica arriving late for the latest tick, after its lock expired, skips it.
+		time.Sleep(2 * opts.Expiry)
+		late := scheduler.New(rs, opts)
+		var again int32
+		Expect(late.Add("job", &once{tick: latest}, func(ctx context.Context) error {
+			atomic.AddInt32(&again, 1)
+			return nil
+		})).To(Succeed())
+		Expect(late.Run(context.Background())).To(Succeed())
+		Expect(atomic.LoadInt32(&again)).To(BeZero())
+	})
+
+	It("cancels a job whose lock is lost", func() {
+		s := scheduler.New(rs, scheduler.Opts{Prefix: "test-scheduler-lost:", Expiry: 60 * time.Millisecond})
+		canceled := make(chan struct{})
+		var once sync.Once
+		Expect(s.Add("lost", scheduler.Every(30*time.Millisecond), func(ctx context.Context) error {
+			statuses, _ := rs.List(ctx, "test-scheduler-lost:lost:*")
+			for _, status := range statuses {
+				rs.ForceUnlock(status.Name)
+			}
+			select {
+			case <-ctx.Done():
+				once.Do(func() { close(canceled) })
+			case <-time.After(time.Second):
+			}
+			return ctx.Err()
+		})).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go s.Run(ctx)
+		Eventually(canceled).Should(BeClosed())
+		cancel()
+	})
+})
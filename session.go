@@ -0,0 +1,203 @@
+package redsync
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ErrSessionDone is returned from Session.Lock after the session was closed or lost.
+var ErrSessionDone = errors.New("redsync: session is closed or lost")
+
+// sessionBatch is the number of extensions pipelined to a node at once.
+const sessionBatch = 128
+
+// Session holds many locks, and extends all of them in a single keepalive loop,
+// like an etcd concurrency session. Every third of the Expiry, the locks are extended
+// with pipelined scripts on each node, rather than with a goroutine and a round trip per lock.
+//
+// A lock that could not be extended on a quorum of nodes is marked lost:
+// its Lease is no longer Valid, and it is dropped from the session.
+// If a keepalive round fails as a whole, because a quorum of nodes could not be reached
+// or the round took longer than the locks are valid, the session is lost:
+// all of its locks are marked lost, and Done is closed.
+type Session struct {
+	rs   *Redsync
+	opts MutexOpts
+
+	mu     sync.Mutex
+	leases map[*Lease]struct{}
+
+	// stop is closed by Close, and done when the session ends.
+	stop chan struct{}
+	done chan struct{}
+	end  sync.Once
+}
+
+// NewSession returns a new Session whose locks are created with opts,
+// and starts its keepalive loop.
+func (r *Redsync) NewSession(opts MutexOpts) *Session {
+	s := &Session{
+		rs:     r,
+		opts:   opts,
+		leases: make(map[*Lease]struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.keepAlive()
+	return s
+}
+
+// Lock acquires a lock on name, like Mutex.Lock with the session's options,
+// and keeps it alive until it is unlocked or the session ends.
+// It returns ErrSessionDone if the session was closed or lost.
+func (s *Session) Lock(name string) (*Lease, error) {
+	select {
+	case <-s.done:
+		return nil, ErrSessionDone
+	default:
+	}
+	lease, err := s.rs.NewMutex(name, s.opts).Lock()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		lease.Unlock()
+		return nil, ErrSessionDone
+	default:
+	}
+	s.leases[lease] = struct{}{}
+	return lease, nil
+}
+
+// Done returns a channel that is closed when the session is closed or lost.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops the keepalive loop and unlocks all locks of the session.
+func (s *Session) Close() {
+	s.end.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+	for _, lease := range s.take() {
+		lease.Unlock()
+	}
+}
+
+func (s *Session) keepAlive() {
+	ticker := time.NewTicker(s.opts.Expiry / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			close(s.done)
+			return
+		case <-ticker.C:
+			if !s.extendAll() {
+				for _, lease := range s.take() {
+					lease.lose()
+				}
+				close(s.done)
+				return
+			}
+		}
+	}
+}
+
+// take removes and returns all leases of the session.
+func (s *Session) take() []*Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := make([]*Lease, 0, len(s.leases))
+	for lease := range s.leases {
+		leases = append(leases, lease)
+	}
+	s.leases = make(map[*Lease]struct{})
+	return leases
+}
+
+// extendAll extends the session's locks, and returns false if the session is lost.
+func (s *Session) extendAll() bool {
+	var leases []*Lease
+	s.mu.Lock()
+	for lease := range s.leases {
+		select {
+		case <-lease.done:
+			delete(s.leases, lease)
+		default:
+			leases = append(leases, lease)
+		}
+	}
+	s.mu.Unlock()
+	if len(leases) == 0 {
+		return true
+	}
+
+	m := leases[0].mutex
+	start := time.Now()
+	counts := make([]int, len(leases))
+	reached := 0
+	for _, pool := range m.pools {
+		if extendBatches(pool, leases, counts) == nil {
+			reached++
+		}
+	}
+	until := m.validUntil(start)
+	if reached < m.quorum || !time.Now().Before(until) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, lease := range leases {
+		if counts[i] >= m.quorum {
+			lease.setUntil(until)
+		} else {
+			lease.lose()
+			delete(s.leases, lease)
+		}
+	}
+	return true
+}
+
+// extendBatches extends leases on pool, pipelining sessionBatch scripts at a time,
+// and increments the counts of the leases that were extended.
+func extendBatches(pool *redis.Pool, leases []*Lease, counts []int) error {
+	conn := pool.Get()
+	defer conn.Close()
+	if err := extendScript.Load(conn); err != nil {
+		return err
+	}
+	for start := 0; start < len(leases); start += sessionBatch {
+		batch := leases[start:]
+		if len(batch) > sessionBatch {
+			batch = batch[:sessionBatch]
+		}
+		for _, lease := range batch {
+			err := extendScript.SendHash(conn, lease.mutex.name, lease.value, int(lease.mutex.expiry/time.Millisecond))
+			if err != nil {
+				return err
+			}
+		}
+		if err := conn.Flush(); err != nil {
+			return err
+		}
+		for i := range batch {
+			status, err := redis.Int(conn.Receive())
+			if _, ok := err.(redis.Error); err != nil && !ok {
+				return err
+			}
+			if err == nil && status != 0 {
+				counts[start+i]++
+			}
+		}
+	}
+	return nil
+}
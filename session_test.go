@@ -0,0 +1,81 @@
+package redsync_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Session", func() {
+	opts := redsync.NonBlocking()
+	opts.Expiry = time.Second
+
+	It("keeps many locks alive", func() {
+		pools := tr.Pools(3)
+		s := redsync.New(pools...).NewSession(opts)
+		defer s.Close()
+		var leases []*redsync.Lease
+		for i := 0; i < 150; i++ {
+			lease, err := s.Lock(fmt.Sprintf("test-session-%d", i))
+			Expect(err).ToNot(HaveOccurred())
+			leases = append(leases, lease)
+		}
+		Expect(leases[7].Unlock()).To(BeTrue())
+
+		time.Sleep(opts.Expiry + opts.Expiry/2)
+		for i, lease := range leases {
+			if i == 7 {
+				continue
+			}
+			Expect(lease.Valid()).To(BeTrue())
+			Expect(lease.IsHeld(context.Background())).To(BeTrue())
+		}
+
+		for _, pool := range pools {
+			conn := pool.Get()
+			_, err := conn.Do("DEL", "test-session-3")
+			conn.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Eventually(leases[3].Valid, 2*opts.Expiry).Should(BeFalse())
+		Expect(leases[4].Valid()).To(BeTrue())
+		Consistently(s.Done()).ShouldNot(BeClosed())
+
+		s.Close()
+		Expect(leases[4].IsHeld(context.Background())).To(BeFalse())
+		_, err := s.Lock("test-session-x")
+		Expect(err).To(Equal(redsync.ErrSessionDone))
+	})
+
+	It("marks all locks lost when the session is lost", func() {
+		var down int32
+		var pools []*redis.Pool
+		for _, pool := range tr.Pools(3) {
+			dial := pool.Dial
+			pools = append(pools, &redis.Pool{Dial: func() (redis.Conn, error) {
+				if atomic.LoadInt32(&down) != 0 {
+					return nil, errors.New("node is down")
+				}
+				return dial()
+			}})
+		}
+		s := redsync.New(pools...).NewSession(opts)
+		defer s.Close()
+		a, err := s.Lock("test-session-lost-a")
+		Expect(err).ToNot(HaveOccurred())
+		b, err := s.Lock("test-session-lost-b")
+		Expect(err).ToNot(HaveOccurred())
+
+		atomic.StoreInt32(&down, 1)
+		Eventually(s.Done(), 2*opts.Expiry).Should(BeClosed())
+		Expect(a.Valid()).To(BeFalse())
+		Expect(b.Valid()).To(BeFalse())
+	})
+})
@@ -0,0 +1,199 @@
+package redsync
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// LockStatus describes the state of a lock key across all nodes.
+// Unlike LockInfo, it is reported even if no value is held on a quorum of nodes,
+// which makes it useful for dashboards and for detecting split-brain situations.
+type LockStatus struct {
+	// Name is the name of the lock.
+	Name string
+	// Nodes is the number of nodes that replied.
+	Nodes int
+	// Total is the number of nodes the Redsync was created with, including those that did not reply.
+	Total int
+	// Held is the number of nodes on which the lock key exists.
+	Held int
+	// Value is the most common value of the lock key across nodes.
+	Value string
+	// Metadata is the owner metadata stored with Value, or nil if there is none.
+	Metadata *Metadata
+	// Agree is the number of nodes holding Value.
+	Agree int
+	// MinTTL and MaxTTL are the smallest and largest remaining time-to-live
+	// of the lock key across the nodes holding it.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+}
+
+// HasQuorum returns true if Value is held on a quorum of all nodes.
+// Nodes that did not reply count as not holding it.
+func (s LockStatus) HasQuorum() bool {
+	return s.Agree > 0 && s.Agree >= Quorum(s.Total)
+}
+
+// SplitBrain returns true if the lock key exists on some nodes,
+// but no value is held on a quorum of them.
+func (s LockStatus) SplitBrain() bool {
+	return s.Held > 0 && !s.HasQuorum()
+}
+
+// Status returns the LockStatus of each of the given lock names, in the same order.
+// Each node is queried once, using MGET and PTTL in a single transaction.
+// Nodes that fail to reply are left out of the result;
+// an error is only returned if no node replied.
+func (r *Redsync) Status(names ...string) ([]LockStatus, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	statuses := make([]LockStatus, len(names))
+	counts := make([]map[string]int, len(names))
+	for i, name := range names {
+		statuses[i].Name = name
+		counts[i] = make(map[string]int)
+	}
+	var firstErr error
+	replied := 0
+	for _, pool := range r.pools {
+		values, ttls, err := statusNode(pool, names)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		replied++
+		for i, value := range values {
+			if value == "" {
+				continue
+			}
+			s := &statuses[i]
+			counts[i][value]++
+			if s.Held == 0 || ttls[i] < s.MinTTL {
+				s.MinTTL = ttls[i]
+			}
+			if ttls[i] > s.MaxTTL {
+				s.MaxTTL = ttls[i]
+			}
+			s.Held++
+		}
+	}
+	if replied == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	for i := range statuses {
+		statuses[i].Nodes = replied
+		statuses[i].Total = len(r.pools)
+		for value, n := range counts[i] {
+			if n > statuses[i].Agree || (n == statuses[i].Agree && value < statuses[i].Value) {
+				statuses[i].Value = value
+				statuses[i].Agree = n
+			}
+		}
+		_, statuses[i].Metadata = decodeValue(statuses[i].Value)
+	}
+	return statuses, nil
+}
+
+// List returns the LockStatus of all lock keys matching the glob-style pattern on any node,
+// sorted by name.
+// Keys are found using SCAN, so List is safe to use against busy servers,
+// but keys created or deleted while listing may or may not be included.
+// Like Status, nodes that fail to reply are skipped;
+// an error is only returned if no node replied, or ctx is done.
+func (r *Redsync) List(ctx context.Context, pattern string) ([]LockStatus, error) {
+	seen := make(map[string]bool)
+	var firstErr error
+	scanned := 0
+	for _, pool := range r.pools {
+		if err := scanNode(ctx, pool, pattern, seen); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		scanned++
+	}
+	if scanned == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return r.Status(names...)
+}
+
+// statusNode returns the values and TTLs of the given keys on a single node.
+// Values are empty for keys that do not exist.
+func statusNode(pool *redis.Pool, names []string) ([]string, []time.Duration, error) {
+	conn := pool.Get()
+	defer conn.Close()
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	conn.Send("MULTI")
+	conn.Send("MGET", args...)
+	for _, name := range names {
+		conn.Send("PTTL", name)
+	}
+	reply, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := redis.Strings(reply[0], nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	ttls := make([]time.Duration, len(names))
+	for i := range names {
+		pttl, err := redis.Int64(reply[i+1], nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pttl > 0 {
+			ttls[i] = time.Duration(pttl) * time.Millisecond
+		}
+	}
+	return values, ttls, nil
+}
+
+// scanNode adds the keys matching pattern on a single node to seen.
+func scanNode(ctx context.Context, pool *redis.Pool, pattern string, seen map[string]bool) error {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cursor := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reply, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		var keys []string
+		if _, err := redis.Scan(reply, &cursor, &keys); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			seen[key] = true
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
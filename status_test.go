@@ -0,0 +1,132 @@
+package redsync_test
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rgalanakis/redsync"
+)
+
+var _ = Describe("Status", func() {
+	setOn := func(pools []*redis.Pool, name, value string) {
+		for _, pool := range pools {
+			conn := pool.Get()
+			_, err := conn.Do("SET", name, value, "PX", 10000)
+			conn.Close()
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	It("reports how many nodes hold each lock and whether they agree", func() {
+		pools := tr.Pools(4)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-status-held", redsync.NonBlocking())
+		lease := mustLock(mutex)
+		defer lease.Unlock()
+		setOn(pools[:1], "test-status-minority", "a")
+		setOn(pools[1:2], "test-status-minority", "b")
+
+		statuses, err := rs.Status("test-status-held", "test-status-minority", "test-status-missing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses).To(HaveLen(3))
+
+		held := statuses[0]
+		Expect(held.Name).To(Equal("test-status-held"))
+		Expect(held.Nodes).To(Equal(4))
+		Expect(held.Held).To(Equal(4))
+		Expect(held.Agree).To(Equal(4))
+		Expect(held.Value).To(Equal(lease.Value()))
+		Expect(held.MinTTL).To(BeNumerically(">", 0))
+		Expect(held.MaxTTL).To(BeNumerically(">=", held.MinTTL))
+		Expect(held.HasQuorum()).To(BeTrue())
+		Expect(held.SplitBrain()).To(BeFalse())
+
+		minority := statuses[1]
+		Expect(minority.Held).To(Equal(2))
+		Expect(minority.Agree).To(Equal(1))
+		Expect(minority.Value).To(Equal("a"))
+		Expect(minority.HasQuorum()).To(BeFalse())
+		Expect(minority.SplitBrain()).To(BeTrue())
+
+		missing := statuses[2]
+		Expect(missing.Held).To(Equal(0))
+		Expect(missing.Value).To(BeEmpty())
+		Expect(missing.HasQuorum()).To(BeFalse())
+		Expect(missing.SplitBrain()).To(BeFalse())
+	})
+
+	It("skips nodes that do not reply", func() {
+		pools := append(tr.Pools(2), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		setOn(pools[:2], "test-status-down", "x")
+		statuses, err := redsync.New(pools...).Status("test-status-down")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses[0].Nodes).To(Equal(2))
+		Expect(statuses[0].Total).To(Equal(3))
+		Expect(statuses[0].HasQuorum()).To(BeTrue())
+	})
+
+	It("counts nodes that do not reply towards the quorum", func() {
+		down := &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")}
+		pools := append(tr.Pools(1), down, down)
+		setOn(pools[:1], "test-status-mostly-down", "x")
+		statuses, err := redsync.New(pools...).Status("test-status-mostly-down")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses[0].Nodes).To(Equal(1))
+		Expect(statuses[0].Agree).To(Equal(1))
+		Expect(statuses[0].HasQuorum()).To(BeFalse())
+		Expect(statuses[0].SplitBrain()).To(BeTrue())
+	})
+
+	It("errors if no node replies", func() {
+		pools := []*redis.Pool{{Dial: redsync.TcpDialer("127.0.0.1:1")}}
+		_, err := redsync.New(pools...).Status("test-status-error")
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	})
+})
+
+var _ = Describe("List", func() {
+	It("finds lock keys matching a pattern on any node", func() {
+		pools := tr.Pools(3)
+		rs := redsync.New(pools...)
+		mutex := rs.NewMutex("test-list-b", redsync.NonBlocking())
+		lease := mustLock(mutex)
+		defer lease.Unlock()
+		conn := pools[2].Get()
+		_, err := conn.Do("SET", "test-list-a", "stray", "PX", 10000)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		statuses, err := rs.List(context.Background(), "test-list-*")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[0].Name).To(Equal("test-list-a"))
+		Expect(statuses[0].SplitBrain()).To(BeTrue())
+		Expect(statuses[1].Name).To(Equal("test-list-b"))
+		Expect(statuses[1].HasQuorum()).To(BeTrue())
+	})
+
+	It("skips nodes that do not reply", func() {
+		pools := append(tr.Pools(2), &redis.Pool{Dial: redsync.TcpDialer("127.0.0.1:1")})
+		conn := pools[0].Get()
+		_, err := conn.Do("SET", "test-list-down", "x", "PX", 10000)
+		conn.Close()
+		Expect(err).ToNot(HaveOccurred())
+
+		statuses, err := redsync.New(pools...).List(context.Background(), "test-list-down*")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].Nodes).To(Equal(2))
+
+		_, err = redsync.New(pools[2:]...).List(context.Background(), "*")
+		Expect(err).To(MatchError(ContainSubstring("connection refused")))
+	})
+
+	It("stops when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := redsync.New(tr.Pools(1)...).List(ctx, "*")
+		Expect(err).To(Equal(context.Canceled))
+	})
+})
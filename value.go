@@ -0,0 +1,134 @@
+package redsync
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueGenerator functions return the token that is stored in lock keys to identify their holder.
+// They are passed the keys the token is stored in, which are the name of a Mutex or PathMutex,
+// or the sorted names of a MultiMutex, and the owner metadata stored with the token, or nil if there is none.
+// Tokens must be unique among all holders of a lock, or a holder could extend or unlock
+// a lock held by another; random tokens, like those of RandomValue, are the default.
+type ValueGenerator func(keys []string, owner *Metadata) (string, error)
+
+// ValueVerifier functions verify a value stored in the lock key with the given name,
+// whose token was created by a ValueGenerator, and return who signed it.
+// For a MultiMutex, the value is verified for each of its names.
+type ValueVerifier func(name, value string) (*Signer, error)
+
+// Signer identifies who created a signed lock token.
+type Signer struct {
+	// ID is the owner ID the token was signed for.
+	ID string
+	// SignedAt is when the token was created.
+	SignedAt time.Time
+}
+
+// ErrBadSignature is returned from a ValueVerifier when a value was not signed with its key
+// for the lock it is stored in.
+var ErrBadSignature = errors.New("redsync: invalid lock token signature")
+
+// RandomValue returns 32 random bytes, base64-encoded.
+func RandomValue() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// randomValue is the default ValueGenerator.
+func randomValue([]string, *Metadata) (string, error) {
+	return RandomValue()
+}
+
+// HMACValueGenerator returns a ValueGenerator creating random tokens that embed ownerID
+// and the time they were created, signed with key using HMAC-SHA256.
+// The token holds a signature for each key it is stored in, which also covers the owner metadata,
+// so a token copied to another lock key, or stored with other metadata, does not verify.
+// Use HMACValueVerifier with the same key, for example in Redsync.ValueVerifier,
+// to prove which service created a lock.
+func HMACValueGenerator(key []byte, ownerID string) ValueGenerator {
+	return func(keys []string, owner *Metadata) (string, error) {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		payload := strings.Join([]string{
+			base64.RawURLEncoding.EncodeToString([]byte(ownerID)),
+			strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+			base64.RawURLEncoding.EncodeToString(b),
+		}, ".")
+		sigs := make([]string, len(keys))
+		for i, k := range keys {
+			sig, err := hmacSign(key, k, owner, payload)
+			if err != nil {
+				return "", err
+			}
+			sigs[i] = sig
+		}
+		return payload + "." + strings.Join(sigs, "."), nil
+	}
+}
+
+// HMACValueVerifier returns a ValueVerifier for values with tokens created by HMACValueGenerator with key.
+// It returns ErrBadSignature for any other value, and for values stored under a lock key
+// they were not signed for, or with other metadata than they were signed for.
+func HMACValueVerifier(key []byte) ValueVerifier {
+	return func(name, value string) (*Signer, error) {
+		token, owner := decodeValue(value)
+		parts := strings.Split(token, ".")
+		if len(parts) < 4 {
+			return nil, ErrBadSignature
+		}
+		sig, err := hmacSign(key, name, owner, strings.Join(parts[:3], "."))
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+		signed := false
+		for _, s := range parts[3:] {
+			if hmac.Equal([]byte(s), []byte(sig)) {
+				signed = true
+			}
+		}
+		if !signed {
+			return nil, ErrBadSignature
+		}
+		id, err := base64.RawURLEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+		ms, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, ErrBadSignature
+		}
+		return &Signer{ID: string(id), SignedAt: time.Unix(0, ms*int64(time.Millisecond))}, nil
+	}
+}
+
+// hmacSign returns the signature of a token payload for the lock key name and owner metadata.
+// Each part is prefixed with its length, so parts cannot run into each other.
+func hmacSign(key []byte, name string, owner *Metadata, payload string) (string, error) {
+	var md []byte
+	if owner != nil {
+		var err error
+		if md, err = json.Marshal(owner); err != nil {
+			return "", err
+		}
+	}
+	mac := hmac.New(sha256.New, key)
+	for _, part := range [][]byte{[]byte(name), md, []byte(payload)} {
+		mac.Write([]byte(strconv.Itoa(len(part)) + ":"))
+		mac.Write(part)
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}